@@ -0,0 +1,16 @@
+-- +migrate Up
+CREATE TABLE document_audit_log(
+    id            BIGSERIAL PRIMARY KEY,
+    document_hash TEXT      NOT NULL,
+    action        TEXT      NOT NULL,
+    reason        TEXT      NOT NULL,
+    actor         TEXT      NOT NULL,
+    created_at    TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX document_audit_log_document_hash_idx ON document_audit_log(document_hash);
+CREATE INDEX claims_document_hash_idx ON claims(document_hash);
+
+-- +migrate Down
+DROP INDEX claims_document_hash_idx;
+DROP TABLE document_audit_log;
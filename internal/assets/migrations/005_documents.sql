@@ -0,0 +1,55 @@
+-- +migrate Up
+CREATE TABLE documents(
+    document_hash      TEXT PRIMARY KEY,
+    registration_count INTEGER   NOT NULL DEFAULT 0,
+    is_banned          BOOLEAN   NOT NULL DEFAULT FALSE,
+    ban_reason         TEXT,
+    policy_version     INTEGER   NOT NULL DEFAULT 0,
+    first_seen_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- claims issued before the document hash was introduced have no document
+ALTER TABLE claims
+    ALTER COLUMN document_hash DROP NOT NULL,
+    ALTER COLUMN document_hash DROP DEFAULT;
+UPDATE claims SET document_hash = NULL WHERE document_hash = '';
+
+INSERT INTO documents(document_hash, registration_count, is_banned, ban_reason, first_seen_at)
+SELECT c.document_hash,
+       COUNT(*),
+       BOOL_OR(c.is_banned),
+       CASE
+           WHEN BOOL_OR(c.is_banned) THEN COALESCE(
+               (SELECT a.reason
+                FROM document_audit_log a
+                WHERE a.document_hash = c.document_hash
+                  AND a.action = 'ban'
+                ORDER BY a.id DESC
+                LIMIT 1),
+               'multi-account limit exceeded')
+           END,
+       COALESCE(MIN(c.created_at), NOW())
+FROM claims c
+WHERE c.document_hash IS NOT NULL
+GROUP BY c.document_hash;
+
+ALTER TABLE claims
+    ADD CONSTRAINT claims_document_hash_fkey FOREIGN KEY (document_hash) REFERENCES documents(document_hash),
+    DROP COLUMN is_banned;
+
+-- +migrate Down
+ALTER TABLE claims
+    ADD COLUMN is_banned BOOLEAN NOT NULL DEFAULT FALSE,
+    DROP CONSTRAINT claims_document_hash_fkey;
+
+UPDATE claims c
+SET is_banned = d.is_banned
+FROM documents d
+WHERE c.document_hash = d.document_hash;
+
+UPDATE claims SET document_hash = '' WHERE document_hash IS NULL;
+ALTER TABLE claims
+    ALTER COLUMN document_hash SET DEFAULT '',
+    ALTER COLUMN document_hash SET NOT NULL;
+
+DROP TABLE documents;
@@ -0,0 +1,5 @@
+-- +migrate Up
+ALTER TABLE claims ADD COLUMN revoked_at TIMESTAMP;
+
+-- +migrate Down
+ALTER TABLE claims DROP COLUMN revoked_at;
@@ -0,0 +1,14 @@
+-- +migrate Up
+CREATE TABLE claim_revocations(
+    claim_id        UUID PRIMARY KEY REFERENCES claims(id) ON DELETE CASCADE,
+    attempts        INTEGER   NOT NULL DEFAULT 0,
+    last_error      TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    confirmed_at    TIMESTAMP,
+    created_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX claim_revocations_pending_idx ON claim_revocations(next_attempt_at) WHERE confirmed_at IS NULL;
+
+-- +migrate Down
+DROP TABLE claim_revocations;
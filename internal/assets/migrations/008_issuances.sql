@@ -0,0 +1,23 @@
+-- +migrate Up
+CREATE TABLE issuances(
+    id                BIGSERIAL PRIMARY KEY,
+    user_did          TEXT      NOT NULL,
+    document_hash     TEXT REFERENCES documents(document_hash),
+    issuing_authority BIGINT    NOT NULL,
+    is_adult          BOOLEAN   NOT NULL,
+    expiration        TIMESTAMP,
+    nullifier         TEXT      NOT NULL UNIQUE,
+    salt              TEXT      NOT NULL,
+    status            TEXT      NOT NULL DEFAULT 'pending',
+    claim_id          UUID REFERENCES claims(id),
+    attempts          INTEGER   NOT NULL DEFAULT 0,
+    last_error        TEXT,
+    next_attempt_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+    created_at        TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX issuances_pending_idx ON issuances(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX issuances_user_did_idx ON issuances(user_did);
+
+-- +migrate Down
+DROP TABLE issuances;
@@ -0,0 +1,21 @@
+-- +migrate Up
+CREATE TABLE registrations(
+    id              UUID PRIMARY KEY,
+    user_did        TEXT      NOT NULL,
+    status          TEXT      NOT NULL,
+    request         JSONB,
+    failure_code    TEXT,
+    failure_reason  TEXT,
+    issuance_id     BIGINT REFERENCES issuances(id),
+    claim_id        UUID REFERENCES claims(id),
+    attempts        INTEGER   NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX registrations_verifying_idx ON registrations(next_attempt_at) WHERE status = 'verifying';
+CREATE INDEX registrations_issuance_id_idx ON registrations(issuance_id);
+
+-- +migrate Down
+DROP TABLE registrations;
@@ -0,0 +1,10 @@
+-- +migrate Up
+-- the voting credential was the only type issued before the credential templates
+ALTER TABLE claims ADD COLUMN credential_type TEXT NOT NULL DEFAULT 'VotingCredential';
+ALTER TABLE claims ALTER COLUMN credential_type DROP DEFAULT;
+ALTER TABLE issuances ADD COLUMN credential_type TEXT NOT NULL DEFAULT 'VotingCredential';
+ALTER TABLE issuances ALTER COLUMN credential_type DROP DEFAULT;
+
+-- +migrate Down
+ALTER TABLE issuances DROP COLUMN credential_type;
+ALTER TABLE claims DROP COLUMN credential_type;
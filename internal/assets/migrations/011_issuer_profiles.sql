@@ -0,0 +1,6 @@
+-- +migrate Up
+-- NULL is the default issuer, the issuances recorded before the issuer profiles were introduced
+ALTER TABLE issuances ADD COLUMN issuer_did TEXT;
+
+-- +migrate Down
+ALTER TABLE issuances DROP COLUMN issuer_did;
@@ -0,0 +1,10 @@
+-- +migrate Up
+-- the credential data is unknown for the claims issued before it was persisted
+ALTER TABLE claims ADD COLUMN credential_hash TEXT;
+ALTER TABLE claims ADD COLUMN issuing_authority BIGINT;
+ALTER TABLE claims ADD COLUMN is_adult BOOLEAN;
+
+-- +migrate Down
+ALTER TABLE claims DROP COLUMN is_adult;
+ALTER TABLE claims DROP COLUMN issuing_authority;
+ALTER TABLE claims DROP COLUMN credential_hash;
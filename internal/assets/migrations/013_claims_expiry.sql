@@ -0,0 +1,13 @@
+-- +migrate Up
+ALTER TABLE claims ADD COLUMN expiration TIMESTAMP;
+ALTER TABLE claims ADD COLUMN expired_at TIMESTAMP;
+
+-- the expiration of the claims issued through the outbox is known from their issuances
+UPDATE claims SET expiration = issuances.expiration FROM issuances WHERE issuances.claim_id = claims.id;
+
+CREATE INDEX claims_expiration_idx ON claims(expiration) WHERE expired_at IS NULL AND revoked_at IS NULL;
+
+-- +migrate Down
+DROP INDEX claims_expiration_idx;
+ALTER TABLE claims DROP COLUMN expired_at;
+ALTER TABLE claims DROP COLUMN expiration;
@@ -0,0 +1,68 @@
+-- +migrate Up
+-- the identity trees of the embedded issuers, the ID is the mt_id of the
+-- go-merkletree-sql storage tables below
+CREATE TABLE merkle_trees(
+    id         BIGSERIAL PRIMARY KEY,
+    issuer_did TEXT NOT NULL,
+    type       TEXT NOT NULL,
+    UNIQUE (issuer_did, type)
+);
+
+CREATE TABLE mt_nodes(
+    mt_id   BIGINT   NOT NULL REFERENCES merkle_trees(id),
+    key     BYTEA    NOT NULL,
+    type    SMALLINT NOT NULL,
+    child_l BYTEA,
+    child_r BYTEA,
+    entry   BYTEA,
+    PRIMARY KEY (mt_id, key)
+);
+
+CREATE TABLE mt_roots(
+    mt_id BIGINT PRIMARY KEY REFERENCES merkle_trees(id),
+    key   BYTEA NOT NULL
+);
+
+CREATE TABLE identity_states(
+    id              BIGSERIAL PRIMARY KEY,
+    issuer_did      TEXT      NOT NULL,
+    state           TEXT      NOT NULL,
+    claims_root     TEXT      NOT NULL,
+    revocation_root TEXT      NOT NULL,
+    roots_root      TEXT      NOT NULL,
+    -- NULL for the genesis state
+    previous_state  TEXT,
+    published_at    TIMESTAMP,
+    created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (issuer_did, state)
+);
+
+CREATE TABLE embedded_credentials(
+    id                 UUID PRIMARY KEY,
+    issuer_did         TEXT      NOT NULL,
+    user_did           TEXT      NOT NULL,
+    credential_type    TEXT      NOT NULL,
+    schema_url         TEXT      NOT NULL,
+    schema_hash        TEXT      NOT NULL,
+    credential_subject JSONB     NOT NULL,
+    document_nullifier TEXT      NOT NULL,
+    claim              TEXT      NOT NULL,
+    signature          TEXT      NOT NULL,
+    revocation_nonce   BIGINT    NOT NULL,
+    expiration         TIMESTAMP,
+    revoked            BOOLEAN   NOT NULL DEFAULT FALSE,
+    -- the first state including the claim, NULL until the state transition
+    state              TEXT,
+    created_at         TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (issuer_did, revocation_nonce)
+);
+
+CREATE INDEX embedded_credentials_user_did_idx ON embedded_credentials(user_did);
+CREATE INDEX embedded_credentials_pending_idx ON embedded_credentials(issuer_did) WHERE state IS NULL;
+
+-- +migrate Down
+DROP TABLE embedded_credentials;
+DROP TABLE identity_states;
+DROP TABLE mt_roots;
+DROP TABLE mt_nodes;
+DROP TABLE merkle_trees;
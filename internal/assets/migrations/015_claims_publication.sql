@@ -0,0 +1,12 @@
+-- +migrate Up
+ALTER TABLE claims ADD COLUMN published_state TEXT;
+ALTER TABLE claims ADD COLUMN published_block BIGINT;
+ALTER TABLE claims ADD COLUMN published_at TIMESTAMP;
+
+CREATE INDEX claims_unpublished_idx ON claims(issuer_did, created_at) WHERE published_at IS NULL AND revoked_at IS NULL;
+
+-- +migrate Down
+DROP INDEX claims_unpublished_idx;
+ALTER TABLE claims DROP COLUMN published_at;
+ALTER TABLE claims DROP COLUMN published_block;
+ALTER TABLE claims DROP COLUMN published_state;
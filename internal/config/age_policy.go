@@ -0,0 +1,83 @@
+package config
+
+// AgePolicy sets the age requirements for the credentials of the given type
+// issued for documents of the given issuing authorities. Empty CredentialType
+// or IssuingAuthorities match any value.
+type AgePolicy struct {
+	CredentialType     string  `fig:"credential_type"`
+	IssuingAuthorities []int64 `fig:"issuing_authorities"`
+	// MinAge is the minimal proven age to issue the credential
+	MinAge int `fig:"min_age,required"`
+	// AdultAge is the age from which the credential is issued with isAdult
+	// set, defaults to the verifier adult_age
+	AdultAge int `fig:"adult_age"`
+	// IssueUnderAge allows issuing the credential below MinAge with isAdult
+	// unset, so the verifiers decide on their own whether to accept it
+	IssueUnderAge bool `fig:"issue_under_age"`
+}
+
+// Allows reports whether the credential can be issued for the proven age
+func (p AgePolicy) Allows(age int) bool {
+	return p.IssueUnderAge || age >= p.MinAge
+}
+
+// IsAdult reports whether the proven age reaches the policy adult age
+func (p AgePolicy) IsAdult(age int) bool {
+	return age >= p.AdultAge
+}
+
+// AgePolicy returns the most specific policy for the credential type and the
+// issuing authority: the one matching both of them wins over the one matching
+// only the authority, which wins over the one matching only the type. The
+// default policy is built from allowed_age, adult_age and issue_under_age.
+func (c *VerifierConfig) AgePolicy(credentialType string, issuingAuthority int64) AgePolicy {
+	result := AgePolicy{
+		MinAge:        c.AllowedAge,
+		AdultAge:      c.AdultAge,
+		IssueUnderAge: c.IssueUnderAge,
+	}
+
+	bestScore := 0
+	for _, policy := range c.AgePolicies {
+		score := policy.matchScore(credentialType, issuingAuthority)
+		if score > bestScore {
+			bestScore = score
+			result = policy
+		}
+	}
+
+	if result.AdultAge == 0 {
+		result.AdultAge = c.AdultAge
+	}
+
+	return result
+}
+
+// matchScore is 0 for a policy not applicable to the given values, otherwise
+// the more specific the policy is the higher the score
+func (p AgePolicy) matchScore(credentialType string, issuingAuthority int64) int {
+	score := 1
+
+	if p.CredentialType != "" {
+		if p.CredentialType != credentialType {
+			return 0
+		}
+		score += 1
+	}
+
+	if len(p.IssuingAuthorities) != 0 {
+		found := false
+		for _, authority := range p.IssuingAuthorities {
+			if authority == issuingAuthority {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0
+		}
+		score += 2
+	}
+
+	return score
+}
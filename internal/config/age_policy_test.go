@@ -0,0 +1,142 @@
+package config
+
+import "testing"
+
+func TestAgePolicySelection(t *testing.T) {
+	cfg := &VerifierConfig{
+		AllowedAge: 18,
+		AdultAge:   18,
+		AgePolicies: []AgePolicy{
+			{CredentialType: "TypeCredential", MinAge: 16},
+			{IssuingAuthorities: []int64{1, 2}, MinAge: 21},
+			{CredentialType: "TypeCredential", IssuingAuthorities: []int64{2}, MinAge: 14, AdultAge: 16},
+			{CredentialType: "OtherCredential", IssuingAuthorities: []int64{3}, MinAge: 25},
+			// less specific than the authority policy above, never selected
+			// for its authorities
+			{MinAge: 30},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		credentialType string
+		authority      int64
+		minAge         int
+		adultAge       int
+	}{
+		{"base policy", "VotingCredential", 9, 30, 18},
+		{"type only", "TypeCredential", 9, 16, 18},
+		{"authority only", "VotingCredential", 1, 21, 18},
+		{"authority wins over type", "TypeCredential", 1, 21, 18},
+		{"type and authority win", "TypeCredential", 2, 14, 16},
+		{"other type of the authority", "VotingCredential", 2, 21, 18},
+		{"type and authority both required", "OtherCredential", 2, 21, 18},
+		{"type and authority match", "OtherCredential", 3, 25, 18},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := cfg.AgePolicy(tt.credentialType, tt.authority)
+
+			if policy.MinAge != tt.minAge {
+				t.Errorf("expected min age %d, got %d", tt.minAge, policy.MinAge)
+			}
+
+			if policy.AdultAge != tt.adultAge {
+				t.Errorf("expected adult age %d, got %d", tt.adultAge, policy.AdultAge)
+			}
+		})
+	}
+}
+
+func TestAgePolicyDefault(t *testing.T) {
+	cfg := &VerifierConfig{
+		AllowedAge:    18,
+		AdultAge:      21,
+		IssueUnderAge: true,
+		AgePolicies: []AgePolicy{
+			{CredentialType: "TypeCredential", MinAge: 16},
+		},
+	}
+
+	policy := cfg.AgePolicy("VotingCredential", 1)
+	expected := AgePolicy{MinAge: 18, AdultAge: 21, IssueUnderAge: true}
+	if policy.MinAge != expected.MinAge || policy.AdultAge != expected.AdultAge ||
+		policy.IssueUnderAge != expected.IssueUnderAge {
+		t.Errorf("expected %+v, got %+v", expected, policy)
+	}
+}
+
+func TestAgePolicyMatchScore(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         AgePolicy
+		credentialType string
+		authority      int64
+		score          int
+	}{
+		{"any", AgePolicy{}, "TypeCredential", 1, 1},
+		{"type", AgePolicy{CredentialType: "TypeCredential"}, "TypeCredential", 1, 2},
+		{"other type", AgePolicy{CredentialType: "TypeCredential"}, "OtherCredential", 1, 0},
+		{"authority", AgePolicy{IssuingAuthorities: []int64{1}}, "TypeCredential", 1, 3},
+		{"other authority", AgePolicy{IssuingAuthorities: []int64{1}}, "TypeCredential", 2, 0},
+		{"type and authority", AgePolicy{CredentialType: "TypeCredential", IssuingAuthorities: []int64{1}}, "TypeCredential", 1, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if score := tt.policy.matchScore(tt.credentialType, tt.authority); score != tt.score {
+				t.Errorf("expected score %d, got %d", tt.score, score)
+			}
+		})
+	}
+}
+
+func TestAgePolicyAllows(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  AgePolicy
+		age     int
+		allowed bool
+		adult   bool
+	}{
+		{"below min age", AgePolicy{MinAge: 18, AdultAge: 18}, 17, false, false},
+		{"at min age", AgePolicy{MinAge: 18, AdultAge: 18}, 18, true, true},
+		{"below min age issued under age", AgePolicy{MinAge: 18, AdultAge: 18, IssueUnderAge: true}, 17, true, false},
+		{"below adult age", AgePolicy{MinAge: 16, AdultAge: 21}, 20, true, false},
+		{"at adult age", AgePolicy{MinAge: 16, AdultAge: 21}, 21, true, true},
+		{"above adult age", AgePolicy{MinAge: 16, AdultAge: 21}, 22, true, true},
+		{"issued under age at adult age", AgePolicy{MinAge: 25, AdultAge: 21, IssueUnderAge: true}, 21, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if allowed := tt.policy.Allows(tt.age); allowed != tt.allowed {
+				t.Errorf("expected allowed %t, got %t", tt.allowed, allowed)
+			}
+
+			if adult := tt.policy.IsAdult(tt.age); adult != tt.adult {
+				t.Errorf("expected adult %t, got %t", tt.adult, adult)
+			}
+		})
+	}
+}
+
+func TestAgePolicyIssueUnderAge(t *testing.T) {
+	cfg := &VerifierConfig{
+		AllowedAge: 18,
+		AdultAge:   18,
+		AgePolicies: []AgePolicy{
+			{IssuingAuthorities: []int64{1}, MinAge: 18, IssueUnderAge: true},
+		},
+	}
+
+	if cfg.AgePolicy("VotingCredential", 2).Allows(17) {
+		t.Error("expected base policy to reject under age")
+	}
+
+	policy := cfg.AgePolicy("VotingCredential", 1)
+	if !policy.Allows(17) || policy.IsAdult(17) {
+		t.Error("expected authority policy to issue under age without isAdult")
+	}
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"fmt"
+	"math/big"
+
+	"gitlab.com/distributed_lab/figure/v3"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+)
+
+// The verified document data the credential subject fields and the hash
+// inputs are mapped from
+const (
+	CredentialFieldIssuingAuthority  = "issuing_authority"
+	CredentialFieldIsAdult           = "is_adult"
+	CredentialFieldDocumentNullifier = "document_nullifier"
+	// CredentialFieldCredentialHash is the Poseidon hash of the template hash
+	// inputs, it can be used only in the subject
+	CredentialFieldCredentialHash = "credential_hash"
+)
+
+type CredentialsConfiger interface {
+	CredentialsConfig() *CredentialsConfig
+}
+
+type CredentialsConfig struct {
+	// Templates are the credential types the clients can request, the
+	// issuer claim_type is issued by default
+	Templates []CredentialTemplate `fig:"templates"`
+}
+
+// CredentialTemplate declares how the credential of the given type is built
+// from the verified document data
+type CredentialTemplate struct {
+	Type   string `fig:"type,required"`
+	Schema string `fig:"schema,required"`
+	// Subject maps the credential subject fields to the document data, the
+	// subject id is always set to the user DID
+	Subject []SubjectField `fig:"subject,required"`
+	// Hash lists the inputs of the credential hash, every input is either
+	// the document data field or a decimal constant
+	Hash []string `fig:"hash"`
+
+	// Context is the JSON-LD context of the credential type, the embedded
+	// issuer derives the claim schema hash from it and requires it
+	Context string `fig:"context"`
+	// IndexSlots and ValueSlots map up to two document data fields each to
+	// the claim data slots of the embedded issuer
+	IndexSlots []string `fig:"index_slots"`
+	ValueSlots []string `fig:"value_slots"`
+}
+
+// SubjectField is the credential subject field set from the document data.
+// The subject is a list rather than a map, because the config keys are case
+// insensitive.
+type SubjectField struct {
+	Field  string `fig:"field,required"`
+	Source string `fig:"source,required"`
+}
+
+// VotingCredentialTemplate is the template of the voting credential issued
+// before the templates were introduced
+func VotingCredentialTemplate(claimType, schema, context string) CredentialTemplate {
+	return CredentialTemplate{
+		Type:    claimType,
+		Schema:  schema,
+		Context: context,
+		Subject: []SubjectField{
+			{Field: "isAdult", Source: CredentialFieldIsAdult},
+			{Field: "issuingAuthority", Source: CredentialFieldIssuingAuthority},
+			{Field: "documentNullifier", Source: CredentialFieldDocumentNullifier},
+			{Field: "credentialHash", Source: CredentialFieldCredentialHash},
+		},
+		Hash:       []string{"1", CredentialFieldIssuingAuthority, CredentialFieldDocumentNullifier},
+		IndexSlots: []string{CredentialFieldIssuingAuthority, CredentialFieldDocumentNullifier},
+		ValueSlots: []string{CredentialFieldIsAdult, CredentialFieldCredentialHash},
+	}
+}
+
+// NullifierField is the subject field holding the document nullifier
+func (t CredentialTemplate) NullifierField() string {
+	for _, field := range t.Subject {
+		if field.Source == CredentialFieldDocumentNullifier {
+			return field.Field
+		}
+	}
+
+	return ""
+}
+
+func (t CredentialTemplate) validate() error {
+	if t.NullifierField() == "" {
+		return fmt.Errorf("credential %s: subject must contain %s", t.Type, CredentialFieldDocumentNullifier)
+	}
+
+	for _, field := range t.Subject {
+		switch field.Source {
+		case CredentialFieldIssuingAuthority, CredentialFieldIsAdult, CredentialFieldDocumentNullifier:
+		case CredentialFieldCredentialHash:
+			if len(t.Hash) == 0 {
+				return fmt.Errorf("credential %s: subject field %s requires hash inputs", t.Type, field.Field)
+			}
+		default:
+			return fmt.Errorf("credential %s: subject field %s has unknown source %s", t.Type, field.Field, field.Source)
+		}
+	}
+
+	for _, input := range t.Hash {
+		switch input {
+		case CredentialFieldIssuingAuthority, CredentialFieldIsAdult, CredentialFieldDocumentNullifier:
+		default:
+			if _, ok := new(big.Int).SetString(input, 10); !ok {
+				return fmt.Errorf("credential %s: hash input %s is neither a field nor a constant", t.Type, input)
+			}
+		}
+	}
+
+	for name, slots := range map[string][]string{"index_slots": t.IndexSlots, "value_slots": t.ValueSlots} {
+		if len(slots) > 2 {
+			return fmt.Errorf("credential %s: %s can hold at most 2 fields", t.Type, name)
+		}
+
+		for _, slot := range slots {
+			switch slot {
+			case CredentialFieldIssuingAuthority, CredentialFieldIsAdult, CredentialFieldDocumentNullifier:
+			case CredentialFieldCredentialHash:
+				if len(t.Hash) == 0 {
+					return fmt.Errorf("credential %s: %s field %s requires hash inputs", t.Type, name, slot)
+				}
+			default:
+				return fmt.Errorf("credential %s: %s has unknown field %s", t.Type, name, slot)
+			}
+		}
+	}
+
+	return nil
+}
+
+type credentials struct {
+	once   comfig.Once
+	getter kv.Getter
+}
+
+func NewCredentialsConfiger(getter kv.Getter) CredentialsConfiger {
+	return &credentials{
+		getter: getter,
+	}
+}
+
+func (c *credentials) CredentialsConfig() *CredentialsConfig {
+	return c.once.Do(func() interface{} {
+		var result CredentialsConfig
+
+		err := figure.
+			Out(&result).
+			With(figure.BaseHooks).
+			From(kv.MustGetStringMap(c.getter, "credentials")).
+			Please()
+		if err != nil {
+			panic(err)
+		}
+
+		types := make(map[string]bool)
+		for _, template := range result.Templates {
+			if types[template.Type] {
+				panic(fmt.Errorf("credential %s is declared twice", template.Type))
+			}
+			types[template.Type] = true
+
+			if err := template.validate(); err != nil {
+				panic(err)
+			}
+		}
+
+		return &result
+	}).(*CredentialsConfig)
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"testing"
+
+	"gitlab.com/distributed_lab/kit/kv"
+)
+
+func TestCredentialTemplateValidate(t *testing.T) {
+	valid := func() CredentialTemplate {
+		return VotingCredentialTemplate("VotingCredential", "https://example.com/schema.json", "https://example.com/context.json")
+	}
+
+	tests := []struct {
+		name     string
+		template func() CredentialTemplate
+		wantErr  bool
+	}{
+		{"voting credential", valid, false},
+		{"constant hash input", func() CredentialTemplate {
+			template := valid()
+			template.Hash = []string{"42", CredentialFieldIsAdult}
+			return template
+		}, false},
+		{"missing nullifier", func() CredentialTemplate {
+			template := valid()
+			template.Subject = template.Subject[:1]
+			return template
+		}, true},
+		{"unknown subject source", func() CredentialTemplate {
+			template := valid()
+			template.Subject = append(template.Subject, SubjectField{Field: "age", Source: "age"})
+			return template
+		}, true},
+		{"subject hash without inputs", func() CredentialTemplate {
+			template := valid()
+			template.Hash = nil
+			template.ValueSlots = []string{CredentialFieldIsAdult}
+			return template
+		}, true},
+		{"unknown hash input", func() CredentialTemplate {
+			template := valid()
+			template.Hash = []string{"1", "age"}
+			return template
+		}, true},
+		{"hash input as credential hash", func() CredentialTemplate {
+			template := valid()
+			template.Hash = []string{CredentialFieldCredentialHash}
+			return template
+		}, true},
+		{"fractional hash input", func() CredentialTemplate {
+			template := valid()
+			template.Hash = []string{"1.5"}
+			return template
+		}, true},
+		{"too many index slots", func() CredentialTemplate {
+			template := valid()
+			template.IndexSlots = []string{CredentialFieldIssuingAuthority, CredentialFieldDocumentNullifier, CredentialFieldIsAdult}
+			return template
+		}, true},
+		{"too many value slots", func() CredentialTemplate {
+			template := valid()
+			template.ValueSlots = []string{CredentialFieldIsAdult, CredentialFieldCredentialHash, CredentialFieldIssuingAuthority}
+			return template
+		}, true},
+		{"unknown slot", func() CredentialTemplate {
+			template := valid()
+			template.IndexSlots = []string{"age"}
+			return template
+		}, true},
+		{"slot hash without inputs", func() CredentialTemplate {
+			template := valid()
+			template.Subject = template.Subject[:3]
+			template.Hash = nil
+			return template
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.template().validate()
+			if tt.wantErr && err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCredentialsConfigRejectsInvalidTemplates(t *testing.T) {
+	template := func(slots []interface{}, hash []interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"type":   "PassportCredential",
+			"schema": "https://example.com/schema.json",
+			"subject": []interface{}{
+				map[string]interface{}{"field": "documentNullifier", "source": CredentialFieldDocumentNullifier},
+			},
+			"hash":        hash,
+			"index_slots": slots,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		templates []interface{}
+		wantErr   bool
+	}{
+		{"valid", []interface{}{
+			template([]interface{}{CredentialFieldDocumentNullifier}, []interface{}{"1", CredentialFieldDocumentNullifier}),
+		}, false},
+		{"valid without hash and slots", []interface{}{
+			template(nil, nil),
+		}, false},
+		{"invalid slot", []interface{}{
+			template([]interface{}{"age"}, nil),
+		}, true},
+		{"invalid hash input", []interface{}{
+			template(nil, []interface{}{"1", "age"}),
+		}, true},
+		{"duplicate type", []interface{}{
+			template(nil, nil),
+			template(nil, nil),
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getter := kv.GetterFunc(func(key string) (map[string]interface{}, error) {
+				return map[string]interface{}{"templates": tt.templates}, nil
+			})
+
+			defer func() {
+				recovered := recover()
+				if tt.wantErr && recovered == nil {
+					t.Fatal("expected config loading to fail")
+				}
+				if !tt.wantErr && recovered != nil {
+					t.Fatalf("expected config to load, got %v", recovered)
+				}
+			}()
+
+			NewCredentialsConfiger(getter).CredentialsConfig()
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"time"
+
+	"gitlab.com/distributed_lab/figure/v3"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+)
+
+type ExpirerConfiger interface {
+	ExpirerConfig() *ExpirerConfig
+}
+
+type ExpirerConfig struct {
+	// Period is how often the expired claims are checked
+	Period time.Duration `fig:"period"`
+	// BatchSize is the maximum amount of claims processed per period
+	BatchSize uint64 `fig:"batch_size"`
+	// Revoke schedules the revocation of the expired claims in the issuer,
+	// otherwise they are only marked as expired
+	Revoke bool `fig:"revoke"`
+}
+
+type expirer struct {
+	once   comfig.Once
+	getter kv.Getter
+}
+
+func NewExpirerConfiger(getter kv.Getter) ExpirerConfiger {
+	return &expirer{
+		getter: getter,
+	}
+}
+
+func (e *expirer) ExpirerConfig() *ExpirerConfig {
+	return e.once.Do(func() interface{} {
+		result := ExpirerConfig{
+			Period:    time.Hour,
+			BatchSize: 100,
+		}
+
+		err := figure.
+			Out(&result).
+			With(figure.BaseHooks).
+			From(kv.MustGetStringMap(e.getter, "expirer")).
+			Please()
+		if err != nil {
+			panic(err)
+		}
+
+		return &result
+	}).(*ExpirerConfig)
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"time"
+
+	"gitlab.com/distributed_lab/figure/v3"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+type GistConfiger interface {
+	GistConfig() *GistConfig
+}
+
+type GistConfig struct {
+	// HeadPeriod is how often the latest block and its GIST root are read
+	HeadPeriod time.Duration `fig:"head_period"`
+	// CacheSize is the maximum amount of cached GIST proofs, 0 disables the
+	// cache
+	CacheSize int `fig:"cache_size"`
+	// ReadAttempts is how many times the GIST proof and root are read if
+	// they do not match before the request fails
+	ReadAttempts int `fig:"read_attempts"`
+	// ReadRetryDelay is the delay between the reads
+	ReadRetryDelay time.Duration `fig:"read_retry_delay"`
+}
+
+type gist struct {
+	once   comfig.Once
+	getter kv.Getter
+}
+
+func NewGistConfiger(getter kv.Getter) GistConfiger {
+	return &gist{
+		getter: getter,
+	}
+}
+
+func (g *gist) GistConfig() *GistConfig {
+	return g.once.Do(func() interface{} {
+		result := GistConfig{
+			HeadPeriod:     2 * time.Second,
+			CacheSize:      10000,
+			ReadAttempts:   3,
+			ReadRetryDelay: 200 * time.Millisecond,
+		}
+
+		err := figure.
+			Out(&result).
+			With(figure.BaseHooks).
+			From(kv.MustGetStringMap(g.getter, "gist")).
+			Please()
+		if err != nil {
+			panic(err)
+		}
+
+		if result.ReadAttempts < 1 {
+			panic(errors.New("gist read_attempts must be positive"))
+		}
+
+		return &result
+	}).(*GistConfig)
+}
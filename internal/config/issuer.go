@@ -8,17 +8,57 @@ import (
 	"gitlab.com/distributed_lab/kit/kv"
 	"gitlab.com/distributed_lab/logan/v3/errors"
 	"reflect"
+	"time"
 )
 
 type IssuerConfiger interface {
 	IssuerConfig() *IssuerConfig
 }
 
+// The issuer modes
+const (
+	// IssuerModeNode issues the credentials through the Issuer Node API
+	IssuerModeNode = "node"
+	// IssuerModeEmbedded builds and signs the claims in the service, the
+	// identity trees are kept in the database
+	IssuerModeEmbedded = "embedded"
+)
+
 type IssuerConfig struct {
-	BaseUrl          string   `fig:"base_url,required"`
+	// Mode is either node or embedded, base_url and core_base_url are
+	// required in the node mode
+	Mode    string `fig:"mode"`
+	BaseUrl string `fig:"base_url"`
+	// CoreBaseUrl is the Issuer Node core API serving the W3C credentials
+	// with their proofs
+	CoreBaseUrl      string   `fig:"core_base_url"`
 	DID              *w3c.DID `fig:"did,required"`
 	ClaimType        string   `fig:"claim_type,required"`
 	CredentialSchema string   `fig:"credential_schema,required"`
+	// CredentialContext is the JSON-LD context of the claim type, the
+	// embedded issuer derives the claim schema hash from it
+	CredentialContext string `fig:"credential_context"`
+
+	// Timeout limits every HTTP request to the Issuer Node
+	Timeout time.Duration `fig:"timeout"`
+	// RetryCount is the amount of retries of the idempotent calls, they are
+	// retried on network errors and 5xx responses
+	RetryCount      int           `fig:"retry_count"`
+	RetryMinBackoff time.Duration `fig:"retry_min_backoff"`
+	RetryMaxBackoff time.Duration `fig:"retry_max_backoff"`
+	// BreakerThreshold is the amount of consecutive failed calls that opens
+	// the circuit breaker, 0 disables it
+	BreakerThreshold int `fig:"breaker_threshold"`
+	// BreakerOpenTimeout is how long the open breaker rejects the calls
+	// before a probe call is let through
+	BreakerOpenTimeout time.Duration `fig:"breaker_open_timeout"`
+
+	// KeyPath is the Vault secret holding the private_key of the embedded
+	// issuer, the secret of the issuer login and password is used if empty
+	KeyPath string `fig:"key_path"`
+	// TransitionPeriod is how often the embedded issuer checks whether its
+	// trees changed and records the next state transition
+	TransitionPeriod time.Duration `fig:"transition_period"`
 }
 
 type issuer struct {
@@ -34,21 +74,64 @@ func NewIssuerConfiger(getter kv.Getter) IssuerConfiger {
 
 func (i *issuer) IssuerConfig() *IssuerConfig {
 	return i.once.Do(func() interface{} {
-		var result IssuerConfig
-
-		err := figure.
-			Out(&result).
-			With(figure.BaseHooks, iden3Hooks).
-			From(kv.MustGetStringMap(i.getter, "issuer")).
-			Please()
+		result, err := newIssuerConfig(kv.MustGetStringMap(i.getter, "issuer"))
 		if err != nil {
 			panic(err)
 		}
 
-		return &result
+		return result
 	}).(*IssuerConfig)
 }
 
+// newIssuerConfig parses the issuer config, the optional fields are set to
+// the defaults
+func newIssuerConfig(raw map[string]interface{}) (*IssuerConfig, error) {
+	result := IssuerConfig{
+		Mode:               IssuerModeNode,
+		Timeout:            10 * time.Second,
+		RetryCount:         3,
+		RetryMinBackoff:    100 * time.Millisecond,
+		RetryMaxBackoff:    2 * time.Second,
+		BreakerThreshold:   5,
+		BreakerOpenTimeout: 30 * time.Second,
+		TransitionPeriod:   5 * time.Minute,
+	}
+
+	err := figure.
+		Out(&result).
+		With(figure.BaseHooks, iden3Hooks).
+		From(raw).
+		Please()
+	if err != nil {
+		return nil, err
+	}
+
+	switch result.Mode {
+	case IssuerModeNode:
+		if result.BaseUrl == "" {
+			return nil, errors.New("base_url is required in the node mode")
+		}
+		if result.CoreBaseUrl == "" {
+			return nil, errors.New("core_base_url is required in the node mode")
+		}
+	case IssuerModeEmbedded:
+		if result.CredentialContext == "" {
+			return nil, errors.New("credential_context is required in the embedded mode")
+		}
+	default:
+		return nil, fmt.Errorf("unknown issuer mode %s", result.Mode)
+	}
+
+	return &result, nil
+}
+
+// MaxAttemptDuration is the longest an issuance attempt waits for the Issuer
+// Node: the lookup of the credential, with all its retries, and the credential
+// creation, which is not retried
+func (c *IssuerConfig) MaxAttemptDuration() time.Duration {
+	return c.Timeout*time.Duration(c.RetryCount+2) + c.RetryMaxBackoff*time.Duration(c.RetryCount)
+}
+
 var iden3Hooks = figure.Hooks{
 	"*w3c.DID": func(value interface{}) (reflect.Value, error) {
 		switch v := value.(type) {
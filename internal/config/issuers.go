@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/cast"
+	figure3 "gitlab.com/distributed_lab/figure/v3"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// DefaultIssuerProfile is the name of the issuer from the issuer section
+const DefaultIssuerProfile = "default"
+
+type IssuersConfiger interface {
+	IssuersConfig() *IssuersConfig
+}
+
+type IssuersConfig struct {
+	// Profiles are the issuers besides the default one
+	Profiles []IssuerProfile
+	// Rules select the issuer of the registration, the first matching rule
+	// wins, the default issuer is used if none matches
+	Rules []IssuerRule
+}
+
+// IssuerProfile is an additional Issuer Node identity
+type IssuerProfile struct {
+	Name string
+	// VaultPath is the Vault secret holding the issuer login and password
+	VaultPath string
+	Issuer    *IssuerConfig
+}
+
+// IssuerRule routes the registrations matching all of its non-empty
+// conditions to the profile. CredentialTypes match only the explicitly
+// requested credential type.
+type IssuerRule struct {
+	Profile            string   `fig:"profile,required"`
+	CredentialTypes    []string `fig:"credential_types"`
+	IssuingAuthorities []int64  `fig:"issuing_authorities"`
+}
+
+// Matches reports whether the registration of the requested credential type
+// and the document issuing authority matches the rule
+func (r IssuerRule) Matches(credentialType string, issuingAuthority int64) bool {
+	if len(r.CredentialTypes) != 0 && !contains(r.CredentialTypes, credentialType) {
+		return false
+	}
+
+	if len(r.IssuingAuthorities) != 0 && !contains(r.IssuingAuthorities, issuingAuthority) {
+		return false
+	}
+
+	return true
+}
+
+func contains[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validate checks that the profiles are distinct from each other and from
+// the default issuer, and that the rules refer to the declared profiles
+func (c *IssuersConfig) validate(defaultDID string) error {
+	names := map[string]bool{DefaultIssuerProfile: true}
+	dids := map[string]string{defaultDID: DefaultIssuerProfile}
+	for _, profile := range c.Profiles {
+		if names[profile.Name] {
+			return fmt.Errorf("issuer profile %s is declared twice", profile.Name)
+		}
+		names[profile.Name] = true
+
+		did := profile.Issuer.DID.String()
+		if other, ok := dids[did]; ok {
+			return fmt.Errorf("issuer profiles %s and %s have the same DID %s", other, profile.Name, did)
+		}
+		dids[did] = profile.Name
+	}
+
+	for _, rule := range c.Rules {
+		if !names[rule.Profile] {
+			return fmt.Errorf("issuer rule refers to unknown profile %s", rule.Profile)
+		}
+	}
+
+	return nil
+}
+
+type issuers struct {
+	once           comfig.Once
+	getter         kv.Getter
+	issuerConfiger IssuerConfiger
+}
+
+// NewIssuersConfiger returns the issuer profiles checked against the default
+// issuer
+func NewIssuersConfiger(getter kv.Getter, issuerConfiger IssuerConfiger) IssuersConfiger {
+	return &issuers{
+		getter:         getter,
+		issuerConfiger: issuerConfiger,
+	}
+}
+
+func (i *issuers) IssuersConfig() *IssuersConfig {
+	return i.once.Do(func() interface{} {
+		raw := kv.MustGetStringMap(i.getter, "issuers")
+
+		var result IssuersConfig
+
+		var rawProfiles []interface{}
+		if raw["profiles"] != nil {
+			var err error
+			rawProfiles, err = cast.ToSliceE(raw["profiles"])
+			if err != nil {
+				panic(errors.Wrap(err, "failed to parse issuer profiles"))
+			}
+		}
+
+		for _, rawProfile := range rawProfiles {
+			profile, err := newIssuerProfile(rawProfile)
+			if err != nil {
+				panic(err)
+			}
+
+			result.Profiles = append(result.Profiles, *profile)
+		}
+
+		rules := struct {
+			Rules []IssuerRule `fig:"rules"`
+		}{}
+
+		err := figure3.
+			Out(&rules).
+			With(figure3.BaseHooks).
+			From(raw).
+			Please()
+		if err != nil {
+			panic(errors.Wrap(err, "failed to figure out issuer rules"))
+		}
+		result.Rules = rules.Rules
+
+		if err := result.validate(i.issuerConfiger.IssuerConfig().DID.String()); err != nil {
+			panic(err)
+		}
+
+		return &result
+	}).(*IssuersConfig)
+}
+
+func newIssuerProfile(rawProfile interface{}) (*IssuerProfile, error) {
+	raw, err := cast.ToStringMapE(rawProfile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse issuer profile")
+	}
+
+	profile := IssuerProfile{
+		Name:      cast.ToString(raw["name"]),
+		VaultPath: cast.ToString(raw["vault_path"]),
+	}
+	if profile.Name == "" || profile.VaultPath == "" {
+		return nil, errors.New("issuer profile name and vault_path are required")
+	}
+
+	profile.Issuer, err = newIssuerConfig(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to figure out issuer profile", logan.F{
+			"profile": profile.Name,
+		})
+	}
+
+	return &profile, nil
+}
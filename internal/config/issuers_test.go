@@ -0,0 +1,96 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/iden3/go-iden3-core/v2/w3c"
+)
+
+func TestIssuersConfigValidate(t *testing.T) {
+	profile := func(name, did string) IssuerProfile {
+		parsed, err := w3c.ParseDID(did)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return IssuerProfile{
+			Name:      name,
+			VaultPath: name,
+			Issuer:    &IssuerConfig{DID: parsed},
+		}
+	}
+
+	const defaultDID = "did:example:default"
+
+	tests := []struct {
+		name    string
+		cfg     IssuersConfig
+		wantErr bool
+	}{
+		{"no profiles", IssuersConfig{}, false},
+		{"distinct profiles", IssuersConfig{
+			Profiles: []IssuerProfile{profile("first", "did:example:first"), profile("second", "did:example:second")},
+			Rules:    []IssuerRule{{Profile: "second"}, {Profile: "first"}},
+		}, false},
+		{"rule of default profile", IssuersConfig{
+			Rules: []IssuerRule{{Profile: DefaultIssuerProfile}},
+		}, false},
+		{"duplicate name", IssuersConfig{
+			Profiles: []IssuerProfile{profile("first", "did:example:first"), profile("first", "did:example:second")},
+		}, true},
+		{"default name", IssuersConfig{
+			Profiles: []IssuerProfile{profile(DefaultIssuerProfile, "did:example:first")},
+		}, true},
+		{"duplicate DID", IssuersConfig{
+			Profiles: []IssuerProfile{profile("first", "did:example:first"), profile("second", "did:example:first")},
+		}, true},
+		{"DID of default issuer", IssuersConfig{
+			Profiles: []IssuerProfile{profile("first", defaultDID)},
+		}, true},
+		{"rule of unknown profile", IssuersConfig{
+			Profiles: []IssuerProfile{profile("first", "did:example:first")},
+			Rules:    []IssuerRule{{Profile: "first"}, {Profile: "second"}},
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.validate(defaultDID)
+			if tt.wantErr && err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestIssuerRuleMatches(t *testing.T) {
+	tests := []struct {
+		name             string
+		rule             IssuerRule
+		credentialType   string
+		issuingAuthority int64
+		expected         bool
+	}{
+		{"no conditions", IssuerRule{}, "", 1, true},
+		{"credential type", IssuerRule{CredentialTypes: []string{"A", "B"}}, "B", 1, true},
+		{"other credential type", IssuerRule{CredentialTypes: []string{"A"}}, "B", 1, false},
+		// the credential type matches only when it is requested explicitly
+		{"default credential type", IssuerRule{CredentialTypes: []string{"A"}}, "", 1, false},
+		{"issuing authority", IssuerRule{IssuingAuthorities: []int64{1, 2}}, "A", 2, true},
+		{"other issuing authority", IssuerRule{IssuingAuthorities: []int64{1}}, "A", 2, false},
+		{"both conditions", IssuerRule{CredentialTypes: []string{"A"}, IssuingAuthorities: []int64{1}}, "A", 1, true},
+		{"only credential type", IssuerRule{CredentialTypes: []string{"A"}, IssuingAuthorities: []int64{1}}, "A", 2, false},
+		{"only issuing authority", IssuerRule{CredentialTypes: []string{"A"}, IssuingAuthorities: []int64{1}}, "B", 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if matches := tt.rule.Matches(tt.credentialType, tt.issuingAuthority); matches != tt.expected {
+				t.Errorf("expected %t, got %t", tt.expected, matches)
+			}
+		})
+	}
+}
@@ -18,6 +18,14 @@ type Config interface {
 	VerifierConfiger
 	NetworkConfiger
 	VaultConfiger
+	RevokerConfiger
+	OutboxConfiger
+	RegistrationsConfiger
+	CredentialsConfiger
+	IssuersConfiger
+	ExpirerConfiger
+	WatcherConfiger
+	GistConfiger
 }
 
 type config struct {
@@ -31,18 +39,37 @@ type config struct {
 	VerifierConfiger
 	NetworkConfiger
 	VaultConfiger
+	RevokerConfiger
+	OutboxConfiger
+	RegistrationsConfiger
+	CredentialsConfiger
+	IssuersConfiger
+	ExpirerConfiger
+	WatcherConfiger
+	GistConfiger
 }
 
 func New(getter kv.Getter) Config {
+	issuerConfiger := NewIssuerConfiger(getter)
+	issuersConfiger := NewIssuersConfiger(getter, issuerConfiger)
+
 	return &config{
-		getter:           getter,
-		Databaser:        pgdb.NewDatabaser(getter),
-		Copuser:          copus.NewCopuser(getter),
-		Listenerer:       comfig.NewListenerer(getter),
-		Logger:           comfig.NewLogger(getter, comfig.LoggerOpts{}),
-		IssuerConfiger:   NewIssuerConfiger(getter),
-		VerifierConfiger: NewVerifierConfiger(getter),
-		NetworkConfiger:  NewNetworkConfiger(getter),
-		VaultConfiger:    NewVaultConfiger(getter),
+		getter:                getter,
+		Databaser:             pgdb.NewDatabaser(getter),
+		Copuser:               copus.NewCopuser(getter),
+		Listenerer:            comfig.NewListenerer(getter),
+		Logger:                comfig.NewLogger(getter, comfig.LoggerOpts{}),
+		IssuerConfiger:        issuerConfiger,
+		VerifierConfiger:      NewVerifierConfiger(getter),
+		NetworkConfiger:       NewNetworkConfiger(getter),
+		VaultConfiger:         NewVaultConfiger(getter),
+		RevokerConfiger:       NewRevokerConfiger(getter),
+		OutboxConfiger:        NewOutboxConfiger(getter, issuerConfiger, issuersConfiger),
+		RegistrationsConfiger: NewRegistrationsConfiger(getter),
+		CredentialsConfiger:   NewCredentialsConfiger(getter),
+		IssuersConfiger:       issuersConfiger,
+		ExpirerConfiger:       NewExpirerConfiger(getter),
+		WatcherConfiger:       NewWatcherConfiger(getter),
+		GistConfiger:          NewGistConfiger(getter),
 	}
 }
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"time"
+
+	"gitlab.com/distributed_lab/figure/v3"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+type OutboxConfiger interface {
+	OutboxConfig() *OutboxConfig
+}
+
+type OutboxConfig struct {
+	// Period is how often the pending issuances are checked
+	Period time.Duration `fig:"period"`
+	// BatchSize is the maximum amount of issuances processed per period
+	BatchSize uint64 `fig:"batch_size"`
+	// Lease is how long an issuance is reserved for a single attempt, other
+	// workers do not pick it up until the lease is over. It must exceed the
+	// longest Issuer Node calls of an attempt, so that the credential is
+	// created before the issuance is re-leased.
+	Lease time.Duration `fig:"lease"`
+	// MinRetryDelay and MaxRetryDelay bound the exponential delay between
+	// the attempts to issue the same credential
+	MinRetryDelay time.Duration `fig:"min_retry_delay"`
+	MaxRetryDelay time.Duration `fig:"max_retry_delay"`
+	// MaxAttempts is the amount of attempts after which the issuance fails
+	MaxAttempts int `fig:"max_attempts"`
+}
+
+type outbox struct {
+	once            comfig.Once
+	getter          kv.Getter
+	issuerConfiger  IssuerConfiger
+	issuersConfiger IssuersConfiger
+}
+
+// NewOutboxConfiger returns the outbox config checked against the configs
+// of the issuers
+func NewOutboxConfiger(getter kv.Getter, issuerConfiger IssuerConfiger, issuersConfiger IssuersConfiger) OutboxConfiger {
+	return &outbox{
+		getter:          getter,
+		issuerConfiger:  issuerConfiger,
+		issuersConfiger: issuersConfiger,
+	}
+}
+
+func (o *outbox) OutboxConfig() *OutboxConfig {
+	return o.once.Do(func() interface{} {
+		result := OutboxConfig{
+			Period:        5 * time.Second,
+			BatchSize:     20,
+			Lease:         time.Minute,
+			MinRetryDelay: 10 * time.Second,
+			MaxRetryDelay: 10 * time.Minute,
+			MaxAttempts:   10,
+		}
+
+		err := figure.
+			Out(&result).
+			With(figure.BaseHooks).
+			From(kv.MustGetStringMap(o.getter, "outbox")).
+			Please()
+		if err != nil {
+			panic(err)
+		}
+
+		issuerConfigs := []*IssuerConfig{o.issuerConfiger.IssuerConfig()}
+		for _, profile := range o.issuersConfiger.IssuersConfig().Profiles {
+			issuerConfigs = append(issuerConfigs, profile.Issuer)
+		}
+
+		for _, issuerConfig := range issuerConfigs {
+			if err := validateLease(result.Lease, issuerConfig); err != nil {
+				panic(err)
+			}
+		}
+
+		return &result
+	}).(*OutboxConfig)
+}
+
+// validateLease checks that the issuance attempt of the issuer is over before
+// the lease is, the embedded issuer does not call the Issuer Node
+func validateLease(lease time.Duration, issuerConfig *IssuerConfig) error {
+	if issuerConfig.Mode != IssuerModeNode {
+		return nil
+	}
+
+	if attempt := issuerConfig.MaxAttemptDuration(); lease <= attempt {
+		return errors.From(errors.New("outbox lease must exceed the longest issuance attempt of the issuer"), logan.F{
+			"lease":      lease.String(),
+			"issuer_did": issuerConfig.DID.String(),
+			"attempt":    attempt.String(),
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iden3/go-iden3-core/v2/w3c"
+)
+
+func TestValidateLease(t *testing.T) {
+	did, err := w3c.ParseDID("did:iden3:polygon:amoy:x6x5sor7zpyT5mmpg4fADaTaGG8gBRwhbSAaVfXfr")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the attempt takes up to 10s * 5 + 2s * 3 = 56s
+	nodeIssuer := &IssuerConfig{
+		Mode:            IssuerModeNode,
+		DID:             did,
+		Timeout:         10 * time.Second,
+		RetryCount:      3,
+		RetryMaxBackoff: 2 * time.Second,
+	}
+	embeddedIssuer := *nodeIssuer
+	embeddedIssuer.Mode = IssuerModeEmbedded
+
+	tests := []struct {
+		name    string
+		lease   time.Duration
+		issuer  *IssuerConfig
+		wantErr bool
+	}{
+		{"lease exceeding attempt", time.Minute, nodeIssuer, false},
+		{"lease equal to attempt", 56 * time.Second, nodeIssuer, true},
+		{"lease shorter than attempt", 30 * time.Second, nodeIssuer, true},
+		{"embedded issuer", time.Second, &embeddedIssuer, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateLease(tt.lease, tt.issuer)
+			if tt.wantErr && err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		})
+	}
+}
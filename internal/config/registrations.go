@@ -0,0 +1,58 @@
+package config
+
+import (
+	"time"
+
+	"gitlab.com/distributed_lab/figure/v3"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+)
+
+type RegistrationsConfiger interface {
+	RegistrationsConfig() *RegistrationsConfig
+}
+
+type RegistrationsConfig struct {
+	// Period is how often the asynchronous registrations are checked
+	Period time.Duration `fig:"period"`
+	// BatchSize is the maximum amount of registrations processed per period
+	BatchSize uint64 `fig:"batch_size"`
+	// Lease is how long a registration is reserved for a single attempt
+	Lease time.Duration `fig:"lease"`
+	// MaxAttempts is the amount of attempts after which a registration
+	// failing with internal errors is marked as failed
+	MaxAttempts int `fig:"max_attempts"`
+}
+
+type registrations struct {
+	once   comfig.Once
+	getter kv.Getter
+}
+
+func NewRegistrationsConfiger(getter kv.Getter) RegistrationsConfiger {
+	return &registrations{
+		getter: getter,
+	}
+}
+
+func (r *registrations) RegistrationsConfig() *RegistrationsConfig {
+	return r.once.Do(func() interface{} {
+		result := RegistrationsConfig{
+			Period:      time.Second,
+			BatchSize:   10,
+			Lease:       time.Minute,
+			MaxAttempts: 5,
+		}
+
+		err := figure.
+			Out(&result).
+			With(figure.BaseHooks).
+			From(kv.MustGetStringMap(r.getter, "registrations")).
+			Please()
+		if err != nil {
+			panic(err)
+		}
+
+		return &result
+	}).(*RegistrationsConfig)
+}
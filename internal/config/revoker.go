@@ -0,0 +1,61 @@
+package config
+
+import (
+	"time"
+
+	"gitlab.com/distributed_lab/figure/v3"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+)
+
+type RevokerConfiger interface {
+	RevokerConfig() *RevokerConfig
+}
+
+type RevokerConfig struct {
+	// Period is how often the pending revocations are checked
+	Period time.Duration `fig:"period"`
+	// BatchSize is the maximum amount of claims processed per period
+	BatchSize uint64 `fig:"batch_size"`
+	// Lease is how long a revocation is reserved for a single attempt, other
+	// instances do not pick it up until the lease is over
+	Lease time.Duration `fig:"lease"`
+	// MinRetryDelay and MaxRetryDelay bound the exponential delay between
+	// the attempts to revoke the same claim
+	MinRetryDelay time.Duration `fig:"min_retry_delay"`
+	MaxRetryDelay time.Duration `fig:"max_retry_delay"`
+}
+
+type revoker struct {
+	once   comfig.Once
+	getter kv.Getter
+}
+
+func NewRevokerConfiger(getter kv.Getter) RevokerConfiger {
+	return &revoker{
+		getter: getter,
+	}
+}
+
+func (r *revoker) RevokerConfig() *RevokerConfig {
+	return r.once.Do(func() interface{} {
+		result := RevokerConfig{
+			Period:        30 * time.Second,
+			BatchSize:     50,
+			Lease:         time.Minute,
+			MinRetryDelay: 30 * time.Second,
+			MaxRetryDelay: time.Hour,
+		}
+
+		err := figure.
+			Out(&result).
+			With(figure.BaseHooks).
+			From(kv.MustGetStringMap(r.getter, "revoker")).
+			Please()
+		if err != nil {
+			panic(err)
+		}
+
+		return &result
+	}).(*RevokerConfig)
+}
@@ -2,11 +2,20 @@ package config
 
 import (
 	"os"
+	"runtime"
 	"time"
 
 	"gitlab.com/distributed_lab/figure/v3"
 	"gitlab.com/distributed_lab/kit/comfig"
 	"gitlab.com/distributed_lab/kit/kv"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+const (
+	defaultQueueSizePerWorker = 16
+	defaultBatchTimeout       = 10 * time.Millisecond
+	defaultRetryAfter         = 5 * time.Second
+	defaultAdultAge           = 18
 )
 
 type VerifierConfiger interface {
@@ -14,12 +23,38 @@ type VerifierConfiger interface {
 }
 
 type VerifierConfig struct {
-	VerificationKeys    map[string][]byte
-	MasterCerts         []byte
-	AllowedAge          int
-	RegistrationTimeout time.Duration
-	MultiAccMinLimit    int
-	MultiAccMaxLimit    int
+	VerificationKeys      map[string][]byte
+	VerificationKeysPaths map[string]string
+	MasterCerts           []byte
+	AllowedAge            int
+	AdultAge              int
+	IssueUnderAge         bool
+	AgePolicies           []AgePolicy
+	RegistrationTimeout   time.Duration
+	MultiAccMinLimit      int
+	MultiAccMaxLimit      int
+	// MultiAccPolicyVersion is recorded for the documents evaluated with the
+	// current multi-account limits, bump it when the limits are changed
+	MultiAccPolicyVersion int
+	// ExposeBanDetails adds the ban reason and the multi-account limit to
+	// the error responses, they are hidden by default
+	ExposeBanDetails bool
+	// ReregistrationCooldown is the minimum time between the document claim
+	// issuance and its re-registration to another DID
+	ReregistrationCooldown time.Duration
+
+	// Workers is the amount of proofs verified in parallel
+	Workers int
+	// QueueSize is the amount of proofs waiting for a free worker,
+	// requests above it are rejected
+	QueueSize int
+	// BatchSize is the maximum amount of proofs checked with a single
+	// pairing, 1 disables batching
+	BatchSize int
+	// BatchTimeout is how long a worker waits to fill the batch
+	BatchTimeout time.Duration
+	// RetryAfter is returned to clients rejected because of the full queue
+	RetryAfter time.Duration
 }
 
 type verifier struct {
@@ -39,10 +74,26 @@ func (v *verifier) VerifierConfig() *VerifierConfig {
 			VerificationKeysPaths map[string]string `fig:"verification_keys_paths,required"`
 			MasterCertsPath       string            `fig:"master_certs_path,required"`
 			AllowedAge            int               `fig:"allowed_age,required"`
+			AdultAge              int               `fig:"adult_age"`
+			IssueUnderAge         bool              `fig:"issue_under_age"`
+			AgePolicies           []AgePolicy       `fig:"age_policies"`
 			MultiAccMinLimit      int               `fig:"multi_acc_min_limit,required"`
 			MultiAccMaxLimit      int               `fig:"multi_acc_max_limit,required"`
+			MultiAccPolicyVersion int               `fig:"multi_acc_policy_version"`
+			ExposeBanDetails      bool              `fig:"expose_ban_details"`
 			RegistrationTimeout   time.Duration     `fig:"registration_timeout"`
-		}{}
+			Workers               int               `fig:"workers"`
+			QueueSize             int               `fig:"queue_size"`
+			BatchSize             int               `fig:"batch_size"`
+			BatchTimeout          time.Duration     `fig:"batch_timeout"`
+			RetryAfter            time.Duration     `fig:"retry_after"`
+		}{
+			AdultAge:     defaultAdultAge,
+			Workers:      runtime.NumCPU(),
+			BatchSize:    1,
+			BatchTimeout: defaultBatchTimeout,
+			RetryAfter:   defaultRetryAfter,
+		}
 
 		err := figure.
 			Out(&newCfg).
@@ -53,6 +104,19 @@ func (v *verifier) VerifierConfig() *VerifierConfig {
 			panic(err)
 		}
 
+		switch {
+		case newCfg.Workers < 1:
+			panic(errors.New("verifier workers must be at least 1"))
+		case newCfg.QueueSize < 0:
+			panic(errors.New("verifier queue_size must not be negative"))
+		case newCfg.BatchSize < 1:
+			panic(errors.New("verifier batch_size must be at least 1"))
+		}
+
+		if newCfg.QueueSize == 0 {
+			newCfg.QueueSize = newCfg.Workers * defaultQueueSizePerWorker
+		}
+
 		verificationKeys := make(map[string][]byte)
 		for algo, path := range newCfg.VerificationKeysPaths {
 			verificationKey, err := os.ReadFile(path)
@@ -69,12 +133,23 @@ func (v *verifier) VerifierConfig() *VerifierConfig {
 		}
 
 		return &VerifierConfig{
-			VerificationKeys:    verificationKeys,
-			MasterCerts:         masterCerts,
-			AllowedAge:          newCfg.AllowedAge,
-			MultiAccMinLimit:    newCfg.MultiAccMinLimit,
-			MultiAccMaxLimit:    newCfg.MultiAccMaxLimit,
-			RegistrationTimeout: newCfg.RegistrationTimeout,
+			VerificationKeys:      verificationKeys,
+			VerificationKeysPaths: newCfg.VerificationKeysPaths,
+			MasterCerts:           masterCerts,
+			AllowedAge:            newCfg.AllowedAge,
+			AdultAge:              newCfg.AdultAge,
+			IssueUnderAge:         newCfg.IssueUnderAge,
+			AgePolicies:           newCfg.AgePolicies,
+			MultiAccMinLimit:      newCfg.MultiAccMinLimit,
+			MultiAccMaxLimit:      newCfg.MultiAccMaxLimit,
+			MultiAccPolicyVersion: newCfg.MultiAccPolicyVersion,
+			ExposeBanDetails:      newCfg.ExposeBanDetails,
+			RegistrationTimeout:   newCfg.RegistrationTimeout,
+			Workers:               newCfg.Workers,
+			QueueSize:             newCfg.QueueSize,
+			BatchSize:             newCfg.BatchSize,
+			BatchTimeout:          newCfg.BatchTimeout,
+			RetryAfter:            newCfg.RetryAfter,
 		}
 	}).(*VerifierConfig)
 }
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"time"
+
+	"gitlab.com/distributed_lab/figure/v3"
+	"gitlab.com/distributed_lab/kit/comfig"
+	"gitlab.com/distributed_lab/kit/kv"
+)
+
+type WatcherConfiger interface {
+	WatcherConfig() *WatcherConfig
+}
+
+type WatcherConfig struct {
+	// Period is how often the issuer states are read from the State contract
+	Period time.Duration `fig:"period"`
+	// BatchSize is the maximum amount of unpublished claims of an issuer
+	// checked per period
+	BatchSize uint64 `fig:"batch_size"`
+}
+
+type watcher struct {
+	once   comfig.Once
+	getter kv.Getter
+}
+
+func NewWatcherConfiger(getter kv.Getter) WatcherConfiger {
+	return &watcher{
+		getter: getter,
+	}
+}
+
+func (w *watcher) WatcherConfig() *WatcherConfig {
+	return w.once.Do(func() interface{} {
+		result := WatcherConfig{
+			Period:    time.Minute,
+			BatchSize: 100,
+		}
+
+		err := figure.
+			Out(&result).
+			With(figure.BaseHooks).
+			From(kv.MustGetStringMap(w.getter, "watcher")).
+			Please()
+		if err != nil {
+			panic(err)
+		}
+
+		return &result
+	}).(*WatcherConfig)
+}
@@ -0,0 +1,32 @@
+package data
+
+import (
+	"time"
+)
+
+const (
+	AuditActionBan   = "ban"
+	AuditActionUnban = "unban"
+	// AuditActionReregister is recorded when the document claim is moved to
+	// another DID
+	AuditActionReregister = "reregister"
+
+	// AuditActorSystem is recorded for the actions taken by the service itself
+	AuditActorSystem = "system"
+)
+
+type AuditLogQ interface {
+	New() AuditLogQ
+	Insert(value AuditLogEntry) error
+	FilterBy(column string, value any) AuditLogQ
+	Select() ([]AuditLogEntry, error)
+}
+
+type AuditLogEntry struct {
+	ID           int64     `db:"id" structs:"-"`
+	DocumentHash string    `db:"document_hash" structs:"document_hash"`
+	Action       string    `db:"action" structs:"action"`
+	Reason       string    `db:"reason" structs:"reason"`
+	Actor        string    `db:"actor" structs:"actor"`
+	CreatedAt    time.Time `db:"created_at" structs:"-"`
+}
@@ -0,0 +1,38 @@
+package data
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ClaimRevocationQ interface {
+	New() ClaimRevocationQ
+	// ScheduleForDocument schedules the revocation of every active claim of
+	// the document, claims that are already scheduled are skipped
+	ScheduleForDocument(documentHash string) error
+	// ScheduleForClaims schedules the revocation of the given claims, claims
+	// that are already scheduled are skipped
+	ScheduleForClaims(claimIDs []uuid.UUID) error
+	Update(fields map[string]any) error
+	FilterBy(column string, value any) ClaimRevocationQ
+	// FilterDue selects the unconfirmed revocations that are due at the
+	// given time
+	FilterDue(now time.Time) ClaimRevocationQ
+	Limit(limit uint64) ClaimRevocationQ
+	// ForUpdateSkipLocked locks the selected revocations, skipping the ones
+	// locked by other transactions
+	ForUpdateSkipLocked() ClaimRevocationQ
+	Select() ([]ClaimRevocation, error)
+}
+
+// ClaimRevocation tracks the revocation of a single claim in the issuer, it is
+// retried until the issuer confirms the credential is revoked
+type ClaimRevocation struct {
+	ClaimID       uuid.UUID  `db:"claim_id" structs:"claim_id"`
+	Attempts      int        `db:"attempts" structs:"attempts"`
+	LastError     *string    `db:"last_error" structs:"last_error"`
+	NextAttemptAt time.Time  `db:"next_attempt_at" structs:"next_attempt_at"`
+	ConfirmedAt   *time.Time `db:"confirmed_at" structs:"confirmed_at"`
+	CreatedAt     time.Time  `db:"created_at" structs:"-"`
+}
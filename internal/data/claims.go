@@ -16,16 +16,41 @@ type ClaimQ interface {
 	Count() (int, error)
 	DeleteByID(id uuid.UUID) error
 	ForUpdate() ClaimQ
+	OrderBy(sort string) ClaimQ
+	// FilterExpired selects the active claims whose credential expired by
+	// the given time and that are not marked as expired yet
+	FilterExpired(now time.Time) ClaimQ
+	// FilterCreatedAfter selects the claims following the given one in the
+	// created_at, id order
+	FilterCreatedAfter(createdAt time.Time, id uuid.UUID) ClaimQ
+	Limit(limit uint64) ClaimQ
 	ResetFilter() ClaimQ
 }
 
 type Claim struct {
-	ID           uuid.UUID `db:"id" structs:"id"`
-	UserDID      string    `db:"user_did" structs:"user_did"`
-	IssuerDID    string    `db:"issuer_did" structs:"issuer_did"`
-	Nullifier    string    `db:"nullifier" structs:"nullifier"`
-	Salt         string    `db:"salt" structs:"salt"`
-	DocumentHash string    `db:"document_hash" structs:"document_hash"`
-	CreatedAt    time.Time `db:"created_at" structs:"-"`
-	IsBanned     bool      `db:"is_banned" structs:"is_banned"`
+	ID             uuid.UUID  `db:"id" structs:"id"`
+	UserDID        string     `db:"user_did" structs:"user_did"`
+	IssuerDID      string     `db:"issuer_did" structs:"issuer_did"`
+	CredentialType string     `db:"credential_type" structs:"credential_type"`
+	Nullifier      string     `db:"nullifier" structs:"nullifier"`
+	Salt           string     `db:"salt" structs:"salt"`
+	DocumentHash   *string    `db:"document_hash" structs:"document_hash"`
+	CreatedAt      time.Time  `db:"created_at" structs:"-"`
+	RevokedAt      *time.Time `db:"revoked_at" structs:"revoked_at"`
+	// ExpiredAt is the time the expired claim was processed, the claims
+	// with it set are treated as inactive
+	ExpiredAt *time.Time `db:"expired_at" structs:"expired_at"`
+	// CredentialHash and the credential attributes below are not set for
+	// the claims issued before they were persisted
+	CredentialHash   *string `db:"credential_hash" structs:"credential_hash"`
+	IssuingAuthority *int64  `db:"issuing_authority" structs:"issuing_authority"`
+	IsAdult          *bool   `db:"is_adult" structs:"is_adult"`
+	// Expiration of the credential, not set for the claims issued before it
+	// was persisted and for the credentials that do not expire
+	Expiration *time.Time `db:"expiration" structs:"expiration"`
+	// PublishedState is the on-chain issuer state the claim was first seen
+	// published in, PublishedBlock and PublishedAt are its block and time
+	PublishedState *string    `db:"published_state" structs:"published_state"`
+	PublishedBlock *int64     `db:"published_block" structs:"published_block"`
+	PublishedAt    *time.Time `db:"published_at" structs:"published_at"`
 }
@@ -0,0 +1,30 @@
+package data
+
+import (
+	"time"
+
+	"gitlab.com/distributed_lab/kit/pgdb"
+)
+
+type DocumentQ interface {
+	New() DocumentQ
+	// InsertIfNotExists inserts the document unless the document with the
+	// same hash is already registered
+	InsertIfNotExists(value Document) error
+	Update(fields map[string]any) error
+	FilterBy(column string, value any) DocumentQ
+	Get() (*Document, error)
+	Select() ([]Document, error)
+	Page(page *pgdb.OffsetPageParams) DocumentQ
+	ForUpdate() DocumentQ
+	ResetFilter() DocumentQ
+}
+
+type Document struct {
+	DocumentHash      string    `db:"document_hash" structs:"document_hash"`
+	RegistrationCount int       `db:"registration_count" structs:"registration_count"`
+	IsBanned          bool      `db:"is_banned" structs:"is_banned"`
+	BanReason         *string   `db:"ban_reason" structs:"ban_reason"`
+	PolicyVersion     int       `db:"policy_version" structs:"policy_version"`
+	FirstSeenAt       time.Time `db:"first_seen_at" structs:"-"`
+}
@@ -0,0 +1,39 @@
+package data
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EmbeddedCredentialQ interface {
+	New() EmbeddedCredentialQ
+	Insert(value EmbeddedCredential) error
+	Update(fields map[string]any) error
+	FilterBy(column string, value any) EmbeddedCredentialQ
+	Get() (*EmbeddedCredential, error)
+	Select() ([]EmbeddedCredential, error)
+}
+
+// EmbeddedCredential is the credential issued by the embedded issuer, the
+// claim is added to the issuer claims tree when it is inserted
+type EmbeddedCredential struct {
+	ID                uuid.UUID `db:"id" structs:"id"`
+	IssuerDID         string    `db:"issuer_did" structs:"issuer_did"`
+	UserDID           string    `db:"user_did" structs:"user_did"`
+	CredentialType    string    `db:"credential_type" structs:"credential_type"`
+	SchemaURL         string    `db:"schema_url" structs:"schema_url"`
+	SchemaHash        string    `db:"schema_hash" structs:"schema_hash"`
+	CredentialSubject string    `db:"credential_subject" structs:"credential_subject"`
+	DocumentNullifier string    `db:"document_nullifier" structs:"document_nullifier"`
+	// Claim is the hex encoded iden3 claim
+	Claim string `db:"claim" structs:"claim"`
+	// Signature is the compressed BabyJubJub signature of the claim hash
+	Signature       string     `db:"signature" structs:"signature"`
+	RevocationNonce int64      `db:"revocation_nonce" structs:"revocation_nonce"`
+	Expiration      *time.Time `db:"expiration" structs:"expiration"`
+	Revoked         bool       `db:"revoked" structs:"revoked"`
+	// State is the first identity state including the claim
+	State     *string   `db:"state" structs:"state"`
+	CreatedAt time.Time `db:"created_at" structs:"-"`
+}
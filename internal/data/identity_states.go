@@ -0,0 +1,31 @@
+package data
+
+import "time"
+
+type IdentityStateQ interface {
+	New() IdentityStateQ
+	Insert(value IdentityState) error
+	Update(fields map[string]any) error
+	// Lock serializes the changes of the issuer identity trees until the
+	// transaction ends
+	Lock(issuerDID string) error
+	FilterBy(column string, value any) IdentityStateQ
+	// Last returns the latest of the selected states, nil if there is none
+	Last() (*IdentityState, error)
+	Select() ([]IdentityState, error)
+}
+
+// IdentityState is the state of the embedded issuer identity. Every state but
+// the genesis one is the transition from the previous state, it is published
+// on-chain in the order of the IDs.
+type IdentityState struct {
+	ID             int64      `db:"id" structs:"-"`
+	IssuerDID      string     `db:"issuer_did" structs:"issuer_did"`
+	State          string     `db:"state" structs:"state"`
+	ClaimsRoot     string     `db:"claims_root" structs:"claims_root"`
+	RevocationRoot string     `db:"revocation_root" structs:"revocation_root"`
+	RootsRoot      string     `db:"roots_root" structs:"roots_root"`
+	PreviousState  *string    `db:"previous_state" structs:"previous_state"`
+	PublishedAt    *time.Time `db:"published_at" structs:"published_at"`
+	CreatedAt      time.Time  `db:"created_at" structs:"-"`
+}
@@ -0,0 +1,52 @@
+package data
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	IssuanceStatusPending = "pending"
+	IssuanceStatusIssued  = "issued"
+	IssuanceStatusFailed  = "failed"
+)
+
+type IssuanceQ interface {
+	New() IssuanceQ
+	// Insert inserts the issuance and returns it with the generated ID
+	Insert(value Issuance) (*Issuance, error)
+	Update(fields map[string]any) error
+	FilterBy(column string, value any) IssuanceQ
+	// FilterDue selects the pending issuances that are due at the given time
+	FilterDue(now time.Time) IssuanceQ
+	Get() (*Issuance, error)
+	Select() ([]Issuance, error)
+	Limit(limit uint64) IssuanceQ
+	// ForUpdateSkipLocked locks the selected rows, skipping the rows locked
+	// by other transactions
+	ForUpdateSkipLocked() IssuanceQ
+	ResetFilter() IssuanceQ
+}
+
+// Issuance is an outbox entry of the credential to be created in the issuer.
+// It is recorded together with the document registration, the claim is
+// inserted once the issuer creates the credential.
+type Issuance struct {
+	ID               int64      `db:"id" structs:"-"`
+	UserDID          string     `db:"user_did" structs:"user_did"`
+	CredentialType   string     `db:"credential_type" structs:"credential_type"`
+	IssuerDID        *string    `db:"issuer_did" structs:"issuer_did"`
+	DocumentHash     *string    `db:"document_hash" structs:"document_hash"`
+	IssuingAuthority int64      `db:"issuing_authority" structs:"issuing_authority"`
+	IsAdult          bool       `db:"is_adult" structs:"is_adult"`
+	Expiration       *time.Time `db:"expiration" structs:"expiration"`
+	Nullifier        string     `db:"nullifier" structs:"nullifier"`
+	Salt             string     `db:"salt" structs:"salt"`
+	Status           string     `db:"status" structs:"status"`
+	ClaimID          *uuid.UUID `db:"claim_id" structs:"claim_id"`
+	Attempts         int        `db:"attempts" structs:"attempts"`
+	LastError        *string    `db:"last_error" structs:"last_error"`
+	NextAttemptAt    time.Time  `db:"next_attempt_at" structs:"next_attempt_at"`
+	CreatedAt        time.Time  `db:"created_at" structs:"-"`
+}
@@ -4,6 +4,16 @@ type MasterQ interface {
 	New() MasterQ
 
 	Claim() ClaimQ
+	Document() DocumentQ
+	AuditLog() AuditLogQ
+	ClaimRevocation() ClaimRevocationQ
+	Issuance() IssuanceQ
+	Registration() RegistrationQ
+	MerkleTree() MerkleTreeQ
+	IdentityState() IdentityStateQ
+	EmbeddedCredential() EmbeddedCredentialQ
 
+	// Transaction runs fn in a transaction, only the queries of db passed to
+	// fn are executed in it
 	Transaction(fn func(db MasterQ) error) error
 }
@@ -0,0 +1,19 @@
+package data
+
+import "github.com/iden3/go-merkletree-sql/v2"
+
+// The identity trees of the embedded issuer
+const (
+	MerkleTreeClaims      = "claims"
+	MerkleTreeRevocations = "revocations"
+	MerkleTreeRoots       = "roots"
+)
+
+type MerkleTreeQ interface {
+	New() MerkleTreeQ
+	// TreeID returns the ID of the issuer tree of the type, the ID is
+	// assigned on the first call
+	TreeID(issuerDID, treeType string) (int64, error)
+	// Storage returns the go-merkletree-sql storage of the tree with the ID
+	Storage(treeID int64) merkletree.Storage
+}
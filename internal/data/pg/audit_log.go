@@ -0,0 +1,46 @@
+package pg
+
+import (
+	sq "github.com/Masterminds/squirrel"
+	"github.com/fatih/structs"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/kit/pgdb"
+)
+
+const auditLogTableName = "document_audit_log"
+
+var auditLogSelector = sq.Select("*").From(auditLogTableName).OrderBy("id")
+
+func NewAuditLogQ(db *pgdb.DB) data.AuditLogQ {
+	return &auditLogQ{
+		db:  db,
+		sel: auditLogSelector,
+	}
+}
+
+type auditLogQ struct {
+	db  *pgdb.DB
+	sel sq.SelectBuilder
+}
+
+func (q *auditLogQ) New() data.AuditLogQ {
+	return NewAuditLogQ(q.db.Clone())
+}
+
+func (q *auditLogQ) Insert(value data.AuditLogEntry) error {
+	clauses := structs.Map(value)
+	stmt := sq.Insert(auditLogTableName).SetMap(clauses)
+	err := q.db.Exec(stmt)
+	return err
+}
+
+func (q *auditLogQ) FilterBy(column string, value any) data.AuditLogQ {
+	q.sel = q.sel.Where(sq.Eq{column: value})
+	return q
+}
+
+func (q *auditLogQ) Select() ([]data.AuditLogEntry, error) {
+	var result []data.AuditLogEntry
+	err := q.db.Select(&result, q.sel)
+	return result, err
+}
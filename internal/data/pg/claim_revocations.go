@@ -0,0 +1,97 @@
+package pg
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/kit/pgdb"
+)
+
+const claimRevocationsTableName = "claim_revocations"
+
+var (
+	claimRevocationsSelector = sq.Select("*").From(claimRevocationsTableName).OrderBy("next_attempt_at")
+	claimRevocationsUpdate   = sq.Update(claimRevocationsTableName)
+)
+
+func NewClaimRevocationsQ(db *pgdb.DB) data.ClaimRevocationQ {
+	return &claimRevocationsQ{
+		db:  db,
+		sel: claimRevocationsSelector,
+		upd: claimRevocationsUpdate,
+	}
+}
+
+type claimRevocationsQ struct {
+	db  *pgdb.DB
+	sel sq.SelectBuilder
+	upd sq.UpdateBuilder
+}
+
+func (q *claimRevocationsQ) New() data.ClaimRevocationQ {
+	return NewClaimRevocationsQ(q.db.Clone())
+}
+
+func (q *claimRevocationsQ) ScheduleForDocument(documentHash string) error {
+	claims := sq.Select("id").
+		From(claimsTableName).
+		Where(sq.Eq{"document_hash": documentHash, "revoked_at": nil})
+	stmt := sq.Insert(claimRevocationsTableName).
+		Columns("claim_id").
+		Select(claims).
+		Suffix("ON CONFLICT (claim_id) DO NOTHING")
+	err := q.db.Exec(stmt)
+	return err
+}
+
+func (q *claimRevocationsQ) ScheduleForClaims(claimIDs []uuid.UUID) error {
+	if len(claimIDs) == 0 {
+		return nil
+	}
+
+	stmt := sq.Insert(claimRevocationsTableName).
+		Columns("claim_id").
+		Suffix("ON CONFLICT (claim_id) DO NOTHING")
+	for _, id := range claimIDs {
+		stmt = stmt.Values(id)
+	}
+
+	err := q.db.Exec(stmt)
+	return err
+}
+
+func (q *claimRevocationsQ) Update(fields map[string]any) error {
+	stmt := q.upd.SetMap(fields)
+	err := q.db.Exec(stmt)
+	return err
+}
+
+func (q *claimRevocationsQ) FilterBy(column string, value any) data.ClaimRevocationQ {
+	eq := sq.Eq{column: value}
+	q.sel = q.sel.Where(eq)
+	q.upd = q.upd.Where(eq)
+	return q
+}
+
+func (q *claimRevocationsQ) FilterDue(now time.Time) data.ClaimRevocationQ {
+	q.sel = q.sel.Where(sq.Eq{"confirmed_at": nil}).Where(sq.LtOrEq{"next_attempt_at": now})
+	return q
+}
+
+func (q *claimRevocationsQ) Limit(limit uint64) data.ClaimRevocationQ {
+	q.sel = q.sel.Limit(limit)
+	return q
+}
+
+func (q *claimRevocationsQ) ForUpdateSkipLocked() data.ClaimRevocationQ {
+	q.sel = q.sel.Suffix("FOR UPDATE SKIP LOCKED")
+	return q
+}
+
+func (q *claimRevocationsQ) Select() ([]data.ClaimRevocation, error) {
+	var result []data.ClaimRevocation
+	err := q.db.Select(&result, q.sel)
+	return result, err
+}
@@ -3,6 +3,7 @@ package pg
 import (
 	"database/sql"
 	"errors"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/fatih/structs"
@@ -95,6 +96,28 @@ func (q *claimsQ) ForUpdate() data.ClaimQ {
 	return q
 }
 
+func (q *claimsQ) OrderBy(sort string) data.ClaimQ {
+	q.sel = q.sel.OrderBy(sort)
+	return q
+}
+
+func (q *claimsQ) FilterExpired(now time.Time) data.ClaimQ {
+	q.sel = q.sel.
+		Where(sq.Eq{"expired_at": nil, "revoked_at": nil}).
+		Where(sq.LtOrEq{"expiration": now})
+	return q
+}
+
+func (q *claimsQ) FilterCreatedAfter(createdAt time.Time, id uuid.UUID) data.ClaimQ {
+	q.sel = q.sel.Where("(created_at, id) > (?, ?)", createdAt, id)
+	return q
+}
+
+func (q *claimsQ) Limit(limit uint64) data.ClaimQ {
+	q.sel = q.sel.Limit(limit)
+	return q
+}
+
 func (q *claimsQ) ResetFilter() data.ClaimQ {
 	q.sel = claimsSelector
 	q.upd = claimsUpdate
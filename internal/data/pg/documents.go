@@ -0,0 +1,89 @@
+package pg
+
+import (
+	"database/sql"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/fatih/structs"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/kit/pgdb"
+)
+
+const documentsTableName = "documents"
+
+var (
+	documentsSelector = sq.Select("*").From(documentsTableName)
+	documentsUpdate   = sq.Update(documentsTableName)
+)
+
+func NewDocumentsQ(db *pgdb.DB) data.DocumentQ {
+	return &documentsQ{
+		db:  db,
+		sel: documentsSelector,
+		upd: documentsUpdate,
+	}
+}
+
+type documentsQ struct {
+	db  *pgdb.DB
+	sel sq.SelectBuilder
+	upd sq.UpdateBuilder
+}
+
+func (q *documentsQ) New() data.DocumentQ {
+	return NewDocumentsQ(q.db.Clone())
+}
+
+func (q *documentsQ) InsertIfNotExists(value data.Document) error {
+	clauses := structs.Map(value)
+	stmt := sq.Insert(documentsTableName).
+		SetMap(clauses).
+		Suffix("ON CONFLICT (document_hash) DO NOTHING")
+	err := q.db.Exec(stmt)
+	return err
+}
+
+func (q *documentsQ) Update(fields map[string]any) error {
+	stmt := q.upd.SetMap(fields)
+	err := q.db.Exec(stmt)
+	return err
+}
+
+func (q *documentsQ) FilterBy(column string, value any) data.DocumentQ {
+	eq := sq.Eq{column: value}
+	q.sel = q.sel.Where(eq)
+	q.upd = q.upd.Where(eq)
+	return q
+}
+
+func (q *documentsQ) Get() (*data.Document, error) {
+	var result data.Document
+	err := q.db.Get(&result, q.sel)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &result, err
+}
+
+func (q *documentsQ) Select() ([]data.Document, error) {
+	var result []data.Document
+	err := q.db.Select(&result, q.sel)
+	return result, err
+}
+
+func (q *documentsQ) Page(page *pgdb.OffsetPageParams) data.DocumentQ {
+	q.sel = page.ApplyTo(q.sel, "first_seen_at", "document_hash")
+	return q
+}
+
+func (q *documentsQ) ForUpdate() data.DocumentQ {
+	q.sel = q.sel.Suffix("FOR UPDATE")
+	return q
+}
+
+func (q *documentsQ) ResetFilter() data.DocumentQ {
+	q.sel = documentsSelector
+	q.upd = documentsUpdate
+	return q
+}
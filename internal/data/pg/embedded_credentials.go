@@ -0,0 +1,71 @@
+package pg
+
+import (
+	"database/sql"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/fatih/structs"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/kit/pgdb"
+)
+
+const embeddedCredentialsTableName = "embedded_credentials"
+
+var (
+	embeddedCredentialsSelector = sq.Select("*").From(embeddedCredentialsTableName)
+	embeddedCredentialsUpdate   = sq.Update(embeddedCredentialsTableName)
+)
+
+func NewEmbeddedCredentialsQ(db *pgdb.DB) data.EmbeddedCredentialQ {
+	return &embeddedCredentialsQ{
+		db:  db,
+		sel: embeddedCredentialsSelector,
+		upd: embeddedCredentialsUpdate,
+	}
+}
+
+type embeddedCredentialsQ struct {
+	db  *pgdb.DB
+	sel sq.SelectBuilder
+	upd sq.UpdateBuilder
+}
+
+func (q *embeddedCredentialsQ) New() data.EmbeddedCredentialQ {
+	return NewEmbeddedCredentialsQ(q.db.Clone())
+}
+
+func (q *embeddedCredentialsQ) Insert(value data.EmbeddedCredential) error {
+	clauses := structs.Map(value)
+	stmt := sq.Insert(embeddedCredentialsTableName).SetMap(clauses)
+	err := q.db.Exec(stmt)
+	return err
+}
+
+func (q *embeddedCredentialsQ) Update(fields map[string]any) error {
+	stmt := q.upd.SetMap(fields)
+	err := q.db.Exec(stmt)
+	return err
+}
+
+func (q *embeddedCredentialsQ) FilterBy(column string, value any) data.EmbeddedCredentialQ {
+	eq := sq.Eq{column: value}
+	q.sel = q.sel.Where(eq)
+	q.upd = q.upd.Where(eq)
+	return q
+}
+
+func (q *embeddedCredentialsQ) Get() (*data.EmbeddedCredential, error) {
+	var result data.EmbeddedCredential
+	err := q.db.Get(&result, q.sel)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &result, err
+}
+
+func (q *embeddedCredentialsQ) Select() ([]data.EmbeddedCredential, error) {
+	var result []data.EmbeddedCredential
+	err := q.db.Select(&result, q.sel)
+	return result, err
+}
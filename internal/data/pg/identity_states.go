@@ -0,0 +1,77 @@
+package pg
+
+import (
+	"database/sql"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/fatih/structs"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/kit/pgdb"
+)
+
+const identityStatesTableName = "identity_states"
+
+var (
+	identityStatesSelector = sq.Select("*").From(identityStatesTableName)
+	identityStatesUpdate   = sq.Update(identityStatesTableName)
+)
+
+func NewIdentityStatesQ(db *pgdb.DB) data.IdentityStateQ {
+	return &identityStatesQ{
+		db:  db,
+		sel: identityStatesSelector,
+		upd: identityStatesUpdate,
+	}
+}
+
+type identityStatesQ struct {
+	db  *pgdb.DB
+	sel sq.SelectBuilder
+	upd sq.UpdateBuilder
+}
+
+func (q *identityStatesQ) New() data.IdentityStateQ {
+	return NewIdentityStatesQ(q.db.Clone())
+}
+
+func (q *identityStatesQ) Insert(value data.IdentityState) error {
+	clauses := structs.Map(value)
+	stmt := sq.Insert(identityStatesTableName).SetMap(clauses)
+	err := q.db.Exec(stmt)
+	return err
+}
+
+func (q *identityStatesQ) Update(fields map[string]any) error {
+	stmt := q.upd.SetMap(fields)
+	err := q.db.Exec(stmt)
+	return err
+}
+
+func (q *identityStatesQ) Lock(issuerDID string) error {
+	err := q.db.ExecRaw("SELECT pg_advisory_xact_lock(hashtext($1))", issuerDID)
+	return err
+}
+
+func (q *identityStatesQ) FilterBy(column string, value any) data.IdentityStateQ {
+	eq := sq.Eq{column: value}
+	q.sel = q.sel.Where(eq)
+	q.upd = q.upd.Where(eq)
+	return q
+}
+
+func (q *identityStatesQ) Last() (*data.IdentityState, error) {
+	var result data.IdentityState
+	stmt := q.sel.OrderBy("id DESC").Limit(1)
+	err := q.db.Get(&result, stmt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &result, err
+}
+
+func (q *identityStatesQ) Select() ([]data.IdentityState, error) {
+	var result []data.IdentityState
+	err := q.db.Select(&result, q.sel.OrderBy("id"))
+	return result, err
+}
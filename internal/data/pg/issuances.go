@@ -0,0 +1,97 @@
+package pg
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/fatih/structs"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/kit/pgdb"
+)
+
+const issuancesTableName = "issuances"
+
+var (
+	issuancesSelector = sq.Select("*").From(issuancesTableName)
+	issuancesUpdate   = sq.Update(issuancesTableName)
+)
+
+func NewIssuancesQ(db *pgdb.DB) data.IssuanceQ {
+	return &issuancesQ{
+		db:  db,
+		sel: issuancesSelector,
+		upd: issuancesUpdate,
+	}
+}
+
+type issuancesQ struct {
+	db  *pgdb.DB
+	sel sq.SelectBuilder
+	upd sq.UpdateBuilder
+}
+
+func (q *issuancesQ) New() data.IssuanceQ {
+	return NewIssuancesQ(q.db.Clone())
+}
+
+func (q *issuancesQ) Insert(value data.Issuance) (*data.Issuance, error) {
+	var result data.Issuance
+	clauses := structs.Map(value)
+	stmt := sq.Insert(issuancesTableName).SetMap(clauses).Suffix("RETURNING *")
+	err := q.db.Get(&result, stmt)
+	return &result, err
+}
+
+func (q *issuancesQ) Update(fields map[string]any) error {
+	stmt := q.upd.SetMap(fields)
+	err := q.db.Exec(stmt)
+	return err
+}
+
+func (q *issuancesQ) FilterBy(column string, value any) data.IssuanceQ {
+	eq := sq.Eq{column: value}
+	q.sel = q.sel.Where(eq)
+	q.upd = q.upd.Where(eq)
+	return q
+}
+
+func (q *issuancesQ) FilterDue(now time.Time) data.IssuanceQ {
+	q.sel = q.sel.
+		Where(sq.Eq{"status": data.IssuanceStatusPending}).
+		Where(sq.LtOrEq{"next_attempt_at": now}).
+		OrderBy("next_attempt_at")
+	return q
+}
+
+func (q *issuancesQ) Get() (*data.Issuance, error) {
+	var result data.Issuance
+	err := q.db.Get(&result, q.sel)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &result, err
+}
+
+func (q *issuancesQ) Select() ([]data.Issuance, error) {
+	var result []data.Issuance
+	err := q.db.Select(&result, q.sel)
+	return result, err
+}
+
+func (q *issuancesQ) Limit(limit uint64) data.IssuanceQ {
+	q.sel = q.sel.Limit(limit)
+	return q
+}
+
+func (q *issuancesQ) ForUpdateSkipLocked() data.IssuanceQ {
+	q.sel = q.sel.Suffix("FOR UPDATE SKIP LOCKED")
+	return q
+}
+
+func (q *issuancesQ) ResetFilter() data.IssuanceQ {
+	q.sel = issuancesSelector
+	q.upd = issuancesUpdate
+	return q
+}
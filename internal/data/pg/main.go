@@ -19,12 +19,48 @@ func (m *masterQ) New() data.MasterQ {
 	return NewMasterQ(m.db)
 }
 
+// Transaction runs fn on a copy of the queries bound to the transaction, the
+// queries of m are never switched to it, so m can be shared by concurrent
+// callers
 func (m *masterQ) Transaction(fn func(q data.MasterQ) error) error {
-	return m.db.Transaction(func() error {
-		return fn(m)
+	q := &masterQ{db: m.db.Clone()}
+	return q.db.Transaction(func() error {
+		return fn(q)
 	})
 }
 
 func (m *masterQ) Claim() data.ClaimQ {
 	return NewClaimsQ(m.db)
 }
+
+func (m *masterQ) Document() data.DocumentQ {
+	return NewDocumentsQ(m.db)
+}
+
+func (m *masterQ) AuditLog() data.AuditLogQ {
+	return NewAuditLogQ(m.db)
+}
+
+func (m *masterQ) ClaimRevocation() data.ClaimRevocationQ {
+	return NewClaimRevocationsQ(m.db)
+}
+
+func (m *masterQ) Issuance() data.IssuanceQ {
+	return NewIssuancesQ(m.db)
+}
+
+func (m *masterQ) Registration() data.RegistrationQ {
+	return NewRegistrationsQ(m.db)
+}
+
+func (m *masterQ) MerkleTree() data.MerkleTreeQ {
+	return NewMerkleTreeQ(m.db)
+}
+
+func (m *masterQ) IdentityState() data.IdentityStateQ {
+	return NewIdentityStatesQ(m.db)
+}
+
+func (m *masterQ) EmbeddedCredential() data.EmbeddedCredentialQ {
+	return NewEmbeddedCredentialsQ(m.db)
+}
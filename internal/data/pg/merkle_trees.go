@@ -0,0 +1,137 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/iden3/go-merkletree-sql/v2"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/kit/pgdb"
+)
+
+const (
+	merkleTreesTableName = "merkle_trees"
+	mtNodesTableName     = "mt_nodes"
+	mtRootsTableName     = "mt_roots"
+)
+
+func NewMerkleTreeQ(db *pgdb.DB) data.MerkleTreeQ {
+	return &merkleTreeQ{
+		db: db,
+	}
+}
+
+type merkleTreeQ struct {
+	db *pgdb.DB
+}
+
+func (q *merkleTreeQ) New() data.MerkleTreeQ {
+	return NewMerkleTreeQ(q.db.Clone())
+}
+
+func (q *merkleTreeQ) TreeID(issuerDID, treeType string) (int64, error) {
+	stmt := sq.Insert(merkleTreesTableName).
+		Columns("issuer_did", "type").
+		Values(issuerDID, treeType).
+		Suffix("ON CONFLICT (issuer_did, type) DO NOTHING")
+	if err := q.db.Exec(stmt); err != nil {
+		return 0, err
+	}
+
+	var result int64
+	err := q.db.Get(&result, sq.Select("id").
+		From(merkleTreesTableName).
+		Where(sq.Eq{"issuer_did": issuerDID, "type": treeType}))
+	return result, err
+}
+
+func (q *merkleTreeQ) Storage(treeID int64) merkletree.Storage {
+	return &merkleTreeStorage{
+		db:     q.db,
+		treeID: treeID,
+	}
+}
+
+// merkleTreeStorage is the storage of a single tree in the tables of the
+// go-merkletree-sql SQL storage, the trees are told apart by mt_id
+type merkleTreeStorage struct {
+	db     *pgdb.DB
+	treeID int64
+}
+
+type mtNode struct {
+	Type   int16  `db:"type"`
+	ChildL []byte `db:"child_l"`
+	ChildR []byte `db:"child_r"`
+	Entry  []byte `db:"entry"`
+}
+
+func (s *merkleTreeStorage) Get(_ context.Context, key []byte) (*merkletree.Node, error) {
+	var stored mtNode
+	stmt := sq.Select("type", "child_l", "child_r", "entry").
+		From(mtNodesTableName).
+		Where(sq.Eq{"mt_id": s.treeID, "key": key})
+	err := s.db.Get(&stored, stmt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, merkletree.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the stored fields are the serialized node without its type
+	raw := []byte{byte(stored.Type)}
+	switch merkletree.NodeType(stored.Type) {
+	case merkletree.NodeTypeMiddle:
+		raw = append(raw, stored.ChildL...)
+		raw = append(raw, stored.ChildR...)
+	case merkletree.NodeTypeLeaf:
+		raw = append(raw, stored.Entry...)
+	}
+
+	return merkletree.NewNodeFromBytes(raw)
+}
+
+func (s *merkleTreeStorage) Put(_ context.Context, key []byte, node *merkletree.Node) error {
+	var childL, childR, entry []byte
+	switch node.Type {
+	case merkletree.NodeTypeMiddle:
+		childL, childR = node.ChildL[:], node.ChildR[:]
+	case merkletree.NodeTypeLeaf:
+		entry = append(append([]byte{}, node.Entry[0][:]...), node.Entry[1][:]...)
+	}
+
+	stmt := sq.Insert(mtNodesTableName).
+		Columns("mt_id", "key", "type", "child_l", "child_r", "entry").
+		Values(s.treeID, key, int16(node.Type), childL, childR, entry).
+		Suffix("ON CONFLICT (mt_id, key) DO NOTHING")
+	err := s.db.Exec(stmt)
+	return err
+}
+
+func (s *merkleTreeStorage) GetRoot(_ context.Context) (*merkletree.Hash, error) {
+	var key []byte
+	stmt := sq.Select("key").From(mtRootsTableName).Where(sq.Eq{"mt_id": s.treeID})
+	err := s.db.Get(&key, stmt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, merkletree.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var root merkletree.Hash
+	copy(root[:], key)
+	return &root, nil
+}
+
+func (s *merkleTreeStorage) SetRoot(_ context.Context, root *merkletree.Hash) error {
+	stmt := sq.Insert(mtRootsTableName).
+		Columns("mt_id", "key").
+		Values(s.treeID, root[:]).
+		Suffix("ON CONFLICT (mt_id) DO UPDATE SET key = EXCLUDED.key")
+	err := s.db.Exec(stmt)
+	return err
+}
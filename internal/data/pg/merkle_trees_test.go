@@ -0,0 +1,128 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/iden3/go-merkletree-sql/v2"
+	"github.com/iden3/go-merkletree-sql/v2/db/memory"
+	"github.com/rarimo/passport-identity-provider/internal/assets"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	migrate "github.com/rubenv/sql-migrate"
+	"gitlab.com/distributed_lab/kit/pgdb"
+)
+
+const testTreeLevels = 40
+
+// newTestMerkleTreeQ returns the merkle tree queries of the migrated database
+// from PGDB_CONN_URL, the test is skipped if it is not set
+func newTestMerkleTreeQ(t *testing.T) data.MerkleTreeQ {
+	t.Helper()
+
+	connURL := os.Getenv("PGDB_CONN_URL")
+	if connURL == "" {
+		t.Skip("skipping, PGDB_CONN_URL not set")
+	}
+
+	db, err := pgdb.Open(pgdb.Opts{
+		URL:                connURL,
+		MaxOpenConnections: 4,
+		MaxIdleConnections: 4,
+	})
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() {
+		db.RawDB().Close()
+	})
+
+	migrations := &migrate.EmbedFileSystemMigrationSource{
+		FileSystem: assets.Migrations,
+		Root:       "migrations",
+	}
+	if _, err := migrate.Exec(db.RawDB(), "postgres", migrations, migrate.Up); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	return NewMerkleTreeQ(db)
+}
+
+func newTestTree(t *testing.T, storage merkletree.Storage) *merkletree.MerkleTree {
+	t.Helper()
+
+	tree, err := merkletree.NewMerkleTree(context.Background(), storage, testTreeLevels)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tree
+}
+
+func TestMerkleTreeStorage(t *testing.T) {
+	q := newTestMerkleTreeQ(t)
+	ctx := context.Background()
+	issuerDID := fmt.Sprintf("did:example:%d", time.Now().UnixNano())
+
+	claimsID, err := q.TreeID(issuerDID, data.MerkleTreeClaims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revocationsID, err := q.TreeID(issuerDID, data.MerkleTreeRevocations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimsID == revocationsID {
+		t.Fatal("expected distinct trees of the issuer")
+	}
+
+	if sameID, err := q.TreeID(issuerDID, data.MerkleTreeClaims); err != nil || sameID != claimsID {
+		t.Fatalf("expected tree %d to be reused, got %d (%v)", claimsID, sameID, err)
+	}
+
+	stored := newTestTree(t, q.Storage(claimsID))
+	expected := newTestTree(t, memory.NewMemoryStorage())
+
+	for i := int64(1); i <= 20; i++ {
+		for _, tree := range []*merkletree.MerkleTree{stored, expected} {
+			if err := tree.Add(ctx, big.NewInt(i), big.NewInt(i*i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if stored.Root().BigInt().Cmp(expected.Root().BigInt()) != 0 {
+		t.Fatalf("expected root %s, got %s", expected.Root().BigInt(), stored.Root().BigInt())
+	}
+
+	reloaded := newTestTree(t, q.Storage(claimsID))
+	if reloaded.Root().BigInt().Cmp(expected.Root().BigInt()) != 0 {
+		t.Fatalf("expected reloaded root %s, got %s", expected.Root().BigInt(), reloaded.Root().BigInt())
+	}
+
+	for _, key := range []int64{1, 7, 20, 21} {
+		proof, _, err := reloaded.GenerateProof(ctx, big.NewInt(key), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedProof, _, err := expected.GenerateProof(ctx, big.NewInt(key), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actualJSON, _ := proof.MarshalJSON()
+		expectedJSON, _ := expectedProof.MarshalJSON()
+		if string(actualJSON) != string(expectedJSON) {
+			t.Errorf("key %d: expected proof %s, got %s", key, expectedJSON, actualJSON)
+		}
+	}
+
+	// the nodes of one tree are not visible in another one
+	other := newTestTree(t, q.Storage(revocationsID))
+	if other.Root().BigInt().Sign() != 0 {
+		t.Fatalf("expected empty revocations tree, got root %s", other.Root().BigInt())
+	}
+}
@@ -0,0 +1,97 @@
+package pg
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/fatih/structs"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/kit/pgdb"
+)
+
+const registrationsTableName = "registrations"
+
+var (
+	registrationsSelector = sq.Select("*").From(registrationsTableName)
+	registrationsUpdate   = sq.Update(registrationsTableName)
+)
+
+func NewRegistrationsQ(db *pgdb.DB) data.RegistrationQ {
+	return &registrationsQ{
+		db:  db,
+		sel: registrationsSelector,
+		upd: registrationsUpdate,
+	}
+}
+
+type registrationsQ struct {
+	db  *pgdb.DB
+	sel sq.SelectBuilder
+	upd sq.UpdateBuilder
+}
+
+func (q *registrationsQ) New() data.RegistrationQ {
+	return NewRegistrationsQ(q.db.Clone())
+}
+
+func (q *registrationsQ) Insert(value data.Registration) (*data.Registration, error) {
+	var result data.Registration
+	clauses := structs.Map(value)
+	stmt := sq.Insert(registrationsTableName).SetMap(clauses).Suffix("RETURNING *")
+	err := q.db.Get(&result, stmt)
+	return &result, err
+}
+
+func (q *registrationsQ) Update(fields map[string]any) error {
+	stmt := q.upd.SetMap(fields)
+	err := q.db.Exec(stmt)
+	return err
+}
+
+func (q *registrationsQ) FilterBy(column string, value any) data.RegistrationQ {
+	eq := sq.Eq{column: value}
+	q.sel = q.sel.Where(eq)
+	q.upd = q.upd.Where(eq)
+	return q
+}
+
+func (q *registrationsQ) FilterDue(now time.Time) data.RegistrationQ {
+	q.sel = q.sel.
+		Where(sq.Eq{"status": data.RegistrationStatusVerifying}).
+		Where(sq.LtOrEq{"next_attempt_at": now}).
+		OrderBy("next_attempt_at")
+	return q
+}
+
+func (q *registrationsQ) Get() (*data.Registration, error) {
+	var result data.Registration
+	err := q.db.Get(&result, q.sel)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &result, err
+}
+
+func (q *registrationsQ) Select() ([]data.Registration, error) {
+	var result []data.Registration
+	err := q.db.Select(&result, q.sel)
+	return result, err
+}
+
+func (q *registrationsQ) Limit(limit uint64) data.RegistrationQ {
+	q.sel = q.sel.Limit(limit)
+	return q
+}
+
+func (q *registrationsQ) ForUpdateSkipLocked() data.RegistrationQ {
+	q.sel = q.sel.Suffix("FOR UPDATE SKIP LOCKED")
+	return q
+}
+
+func (q *registrationsQ) ResetFilter() data.RegistrationQ {
+	q.sel = registrationsSelector
+	q.upd = registrationsUpdate
+	return q
+}
@@ -0,0 +1,51 @@
+package data
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Registration statuses, a registration moves from verifying to issuing and
+// then to issued, or to failed at any point before issued
+const (
+	RegistrationStatusVerifying = "verifying"
+	RegistrationStatusIssuing   = "issuing"
+	RegistrationStatusIssued    = "issued"
+	RegistrationStatusFailed    = "failed"
+)
+
+type RegistrationQ interface {
+	New() RegistrationQ
+	// Insert inserts the registration and returns it with the defaults set
+	Insert(value Registration) (*Registration, error)
+	Update(fields map[string]any) error
+	FilterBy(column string, value any) RegistrationQ
+	// FilterDue selects the registrations waiting for verification that are
+	// due at the given time
+	FilterDue(now time.Time) RegistrationQ
+	Get() (*Registration, error)
+	Select() ([]Registration, error)
+	Limit(limit uint64) RegistrationQ
+	// ForUpdateSkipLocked locks the selected rows, skipping the rows locked
+	// by other transactions
+	ForUpdateSkipLocked() RegistrationQ
+	ResetFilter() RegistrationQ
+}
+
+// Registration is an identity creation request processed asynchronously. The
+// request is kept only until it is verified.
+type Registration struct {
+	ID            uuid.UUID  `db:"id" structs:"id"`
+	UserDID       string     `db:"user_did" structs:"user_did"`
+	Status        string     `db:"status" structs:"status"`
+	Request       *string    `db:"request" structs:"request"`
+	FailureCode   *string    `db:"failure_code" structs:"failure_code"`
+	FailureReason *string    `db:"failure_reason" structs:"failure_reason"`
+	IssuanceID    *int64     `db:"issuance_id" structs:"issuance_id"`
+	ClaimID       *uuid.UUID `db:"claim_id" structs:"claim_id"`
+	Attempts      int        `db:"attempts" structs:"attempts"`
+	NextAttemptAt time.Time  `db:"next_attempt_at" structs:"next_attempt_at"`
+	CreatedAt     time.Time  `db:"created_at" structs:"-"`
+	UpdatedAt     time.Time  `db:"updated_at" structs:"-"`
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+)
+
+// AdminAuth authorizes the requests by the bearer token, tokens are the admin
+// API tokens by the admin names
+func AdminAuth(tokens map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				ape.RenderErr(w, problems.Unauthorized())
+				return
+			}
+
+			for name, adminToken := range tokens {
+				if subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1 {
+					ctx := context.WithValue(r.Context(), adminNameCtxKey, name)
+					next.ServeHTTP(w, r.WithContext(ctx))
+					return
+				}
+			}
+
+			Log(r).Warn("invalid admin token")
+			ape.RenderErr(w, problems.Unauthorized())
+		})
+	}
+}
+
+// AdminName returns the name of the admin authorized by AdminAuth
+func AdminName(r *http.Request) string {
+	return r.Context().Value(adminNameCtxKey).(string)
+}
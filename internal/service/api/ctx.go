@@ -8,8 +8,11 @@ import (
 	stateabi "github.com/iden3/contracts-abi/state/go/abi"
 	"github.com/rarimo/passport-identity-provider/internal/config"
 	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/gist"
 	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+	"github.com/rarimo/passport-identity-provider/internal/service/outbox"
 	"github.com/rarimo/passport-identity-provider/internal/service/vault"
+	"github.com/rarimo/passport-identity-provider/internal/service/verifier"
 	"gitlab.com/distributed_lab/logan/v3"
 )
 
@@ -23,6 +26,10 @@ const (
 	issuerCtxKey
 	vaultClientCtxKey
 	ethClientCtxKey
+	verifierCtxKey
+	adminNameCtxKey
+	outboxCtxKey
+	gistCtxKey
 )
 
 func CtxLog(entry *logan.Entry) func(context.Context) context.Context {
@@ -65,14 +72,14 @@ func StateContract(r *http.Request) *stateabi.State {
 	return r.Context().Value(stateContractKey).(*stateabi.State)
 }
 
-func CtxIssuer(iss *issuer.Issuer) func(context.Context) context.Context {
+func CtxIssuers(issuers *issuer.Registry) func(context.Context) context.Context {
 	return func(ctx context.Context) context.Context {
-		return context.WithValue(ctx, issuerCtxKey, iss)
+		return context.WithValue(ctx, issuerCtxKey, issuers)
 	}
 }
 
-func Issuer(r *http.Request) *issuer.Issuer {
-	return r.Context().Value(issuerCtxKey).(*issuer.Issuer)
+func Issuers(r *http.Request) *issuer.Registry {
+	return r.Context().Value(issuerCtxKey).(*issuer.Registry)
 }
 
 func CtxVaultClient(vaultClient *vault.VaultClient) func(context.Context) context.Context {
@@ -94,3 +101,33 @@ func CtxEthClient(client *ethclient.Client) func(context.Context) context.Contex
 func EthClient(r *http.Request) *ethclient.Client {
 	return r.Context().Value(ethClientCtxKey).(*ethclient.Client)
 }
+
+func CtxVerifier(v *verifier.Verifier) func(context.Context) context.Context {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, verifierCtxKey, v)
+	}
+}
+
+func Verifier(r *http.Request) *verifier.Verifier {
+	return r.Context().Value(verifierCtxKey).(*verifier.Verifier)
+}
+
+func CtxOutbox(o *outbox.Outbox) func(context.Context) context.Context {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, outboxCtxKey, o)
+	}
+}
+
+func Outbox(r *http.Request) *outbox.Outbox {
+	return r.Context().Value(outboxCtxKey).(*outbox.Outbox)
+}
+
+func CtxGist(provider *gist.Provider) func(context.Context) context.Context {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, gistCtxKey, provider)
+	}
+}
+
+func Gist(r *http.Request) *gist.Provider {
+	return r.Context().Value(gistCtxKey).(*gist.Provider)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/api"
+	"github.com/rarimo/passport-identity-provider/internal/service/api/requests"
+	"github.com/rarimo/passport-identity-provider/internal/service/registration"
+	"github.com/rarimo/passport-identity-provider/resources"
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+func BanDocument(w http.ResponseWriter, r *http.Request) {
+	setDocumentBan(w, r, true)
+}
+
+func UnbanDocument(w http.ResponseWriter, r *http.Request) {
+	setDocumentBan(w, r, false)
+}
+
+func setDocumentBan(w http.ResponseWriter, r *http.Request, isBanned bool) {
+	req, err := requests.NewBanDocumentRequest(r)
+	if err != nil {
+		api.Log(r).WithError(err).Error("failed to parse ban document request")
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
+
+	log := api.Log(r).WithFields(logan.F{
+		"document_hash": req.DocumentHash,
+		"is_banned":     isBanned,
+		"admin":         api.AdminName(r),
+	})
+
+	action := data.AuditActionUnban
+	if isBanned {
+		action = data.AuditActionBan
+	}
+
+	var document *data.Document
+	if err := api.MasterQ(r).Transaction(func(db data.MasterQ) error {
+		if isBanned {
+			// a document can be banned before its first registration
+			document, err = registration.LockDocument(db, req.DocumentHash, api.VerifierConfig(r).MultiAccPolicyVersion)
+		} else {
+			document, err = db.Document().FilterBy("document_hash", req.DocumentHash).ForUpdate().Get()
+		}
+		if err != nil {
+			ape.RenderErr(w, problems.InternalError())
+			return errors.Wrap(err, "failed to lock document")
+		}
+
+		if document == nil {
+			ape.RenderErr(w, problems.NotFound())
+			return errors.New("document is not registered")
+		}
+
+		document.IsBanned = isBanned
+		document.BanReason = nil
+		if isBanned {
+			document.BanReason = &req.Data.Attributes.Reason
+		}
+
+		err = db.Document().FilterBy("document_hash", req.DocumentHash).Update(map[string]any{
+			"is_banned":  document.IsBanned,
+			"ban_reason": document.BanReason,
+		})
+		if err != nil {
+			ape.RenderErr(w, problems.InternalError())
+			return errors.Wrap(err, "failed to update document ban")
+		}
+
+		// claims revoked on ban stay revoked after the ban is lifted
+		if isBanned {
+			if err := db.ClaimRevocation().ScheduleForDocument(req.DocumentHash); err != nil {
+				ape.RenderErr(w, problems.InternalError())
+				return errors.Wrap(err, "failed to schedule claims revocation")
+			}
+		}
+
+		if err := db.AuditLog().Insert(data.AuditLogEntry{
+			DocumentHash: req.DocumentHash,
+			Action:       action,
+			Reason:       req.Data.Attributes.Reason,
+			Actor:        api.AdminName(r),
+		}); err != nil {
+			ape.RenderErr(w, problems.InternalError())
+			return errors.Wrap(err, "failed to insert audit log entry")
+		}
+
+		return nil
+	}); err != nil {
+		log.WithError(err).Error("failed to execute SQL transaction")
+		// error was rendered beforehand
+		return
+	}
+
+	log.WithField("reason", req.Data.Attributes.Reason).Info("document ban updated by admin")
+
+	ape.Render(w, resources.DocumentResponse{
+		Data:     newDocument(*document),
+		Included: resources.Included{},
+	})
+}
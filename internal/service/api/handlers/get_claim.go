@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/api"
+	"github.com/rarimo/passport-identity-provider/internal/service/api/requests"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+	"github.com/rarimo/passport-identity-provider/resources"
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// GetClaim returns the claim status combined with the state of its credential
+// in the Issuer Node
+func GetClaim(w http.ResponseWriter, r *http.Request) {
+	id, err := requests.NewGetClaimRequest(r)
+	if err != nil {
+		api.Log(r).WithError(err).Debug("failed to parse get claim request")
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
+
+	log := api.Log(r).WithField("claim_id", id.String())
+
+	claim, err := api.MasterQ(r).Claim().FilterBy("id", id).Get()
+	if err != nil {
+		log.WithError(err).Error("failed to get claim")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	if claim == nil {
+		ape.RenderErr(w, problems.NotFound())
+		return
+	}
+
+	iss, ok := api.Issuers(r).ByDID(claim.IssuerDID)
+	if !ok {
+		log.WithField("issuer_did", claim.IssuerDID).Error("issuer of the claim is not configured")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	attributes := newClaimAttributes(*claim)
+	attributes.CreatedAt = &claim.CreatedAt
+	attributes.RevokedAt = claim.RevokedAt
+	attributes.ExpiredAt = claim.ExpiredAt
+
+	revoked := claim.RevokedAt != nil
+	mtpAvailable := claim.PublishedAt != nil
+
+	cred, err := iss.GetCredential(claim.ID)
+	switch {
+	case err == nil:
+		revoked = revoked || cred.Revoked
+		mtpAvailable = mtpAvailable || cred.HasMTP()
+		if !cred.ExpiresAt.IsZero() {
+			attributes.ExpiresAt = &cred.ExpiresAt
+		}
+	case errors.Cause(err) == issuer.ErrNotFound:
+		// the claim status is still known from the database
+		log.WithError(err).Warn("credential of the claim is missing in the issuer")
+		credentialMissing := true
+		attributes.CredentialMissing = &credentialMissing
+	case issuer.IsTemporary(err):
+		log.WithError(err).Warn("issuer node is not available")
+		renderServiceUnavailable(w, iss.RetryAfter())
+		return
+	default:
+		log.WithError(err).Error("failed to get credential")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	attributes.Revoked = &revoked
+	attributes.MtpAvailable = &mtpAvailable
+
+	ape.Render(w, resources.ClaimResponse{
+		Data: resources.Claim{
+			Key: resources.Key{
+				ID:   claim.ID.String(),
+				Type: resources.CLAIMS,
+			},
+			Attributes: attributes,
+		},
+		Included: resources.Included{},
+	})
+}
+
+// newClaimAttributes returns the claim with the credential hash and the
+// attributes the voting proofs are built from
+func newClaimAttributes(claim data.Claim) resources.ClaimAttributes {
+	return resources.ClaimAttributes{
+		ClaimId:          claim.ID.String(),
+		IssuerDid:        claim.IssuerDID,
+		CredentialType:   &claim.CredentialType,
+		CredentialHash:   claim.CredentialHash,
+		Nullifier:        &claim.Nullifier,
+		IssuingAuthority: claim.IssuingAuthority,
+		IsAdult:          claim.IsAdult,
+		ExpiresAt:        claim.Expiration,
+		PublishedState:   claim.PublishedState,
+		PublishedBlock:   claim.PublishedBlock,
+		PublishedAt:      claim.PublishedAt,
+	}
+}
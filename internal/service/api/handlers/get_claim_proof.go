@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/iden3/go-merkletree-sql/v2"
+	"github.com/rarimo/passport-identity-provider/internal/service/api"
+	"github.com/rarimo/passport-identity-provider/internal/service/api/requests"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+	"github.com/rarimo/passport-identity-provider/resources"
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// GetClaimProof returns the signed claim of the embedded issuer credential
+// with its proofs, the Issuer Node serves them in its credentials
+func GetClaimProof(w http.ResponseWriter, r *http.Request) {
+	id, err := requests.NewGetClaimRequest(r)
+	if err != nil {
+		api.Log(r).WithError(err).Debug("failed to parse get claim proof request")
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
+
+	log := api.Log(r).WithField("claim_id", id.String())
+
+	claim, err := api.MasterQ(r).Claim().FilterBy("id", id).Get()
+	if err != nil {
+		log.WithError(err).Error("failed to get claim")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	if claim == nil {
+		ape.RenderErr(w, problems.NotFound())
+		return
+	}
+
+	iss, ok := api.Issuers(r).ByDID(claim.IssuerDID)
+	if !ok {
+		log.WithField("issuer_did", claim.IssuerDID).Error("issuer of the claim is not configured")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	if !iss.IsEmbedded() {
+		ape.RenderErr(w, problems.NotFound())
+		return
+	}
+
+	proof, err := iss.ClaimProof(claim.ID)
+	if errors.Cause(err) == issuer.ErrNotFound {
+		log.WithError(err).Warn("credential of the claim is missing in the issuer")
+		ape.RenderErr(w, problems.NotFound())
+		return
+	}
+	if err != nil {
+		log.WithError(err).Error("failed to get claim proof")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	authClaim, err := proof.AuthClaim.Hex()
+	if err != nil {
+		log.WithError(err).Error("failed to encode auth claim")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	attributes := resources.ClaimProofAttributes{
+		AuthClaim:            authClaim,
+		AuthClaimMtp:         newMerkleProof(proof.AuthClaimMTP),
+		AuthClaimNonRevProof: newMerkleProof(proof.AuthClaimNonRevProof),
+		Claim:                proof.Credential.Claim,
+		ClaimsRoot:           proof.State.ClaimsRoot,
+		IssuerDid:            proof.Credential.IssuerDID,
+		NonRevProof:          newMerkleProof(proof.NonRevProof),
+		RevocationRoot:       proof.State.RevocationRoot,
+		RootsRoot:            proof.State.RootsRoot,
+		Signature:            proof.Credential.Signature,
+		State:                proof.State.State,
+	}
+
+	if proof.MTP != nil {
+		mtp := newMerkleProof(proof.MTP)
+		attributes.Mtp = &mtp
+	}
+
+	ape.Render(w, resources.ClaimProofResponse{
+		Data: resources.ClaimProof{
+			Key: resources.Key{
+				ID:   claim.ID.String(),
+				Type: resources.CLAIM_PROOFS,
+			},
+			Attributes: attributes,
+		},
+		Included: resources.Included{},
+	})
+}
+
+// newMerkleProof returns the proof with the empty siblings expanded, the same
+// as the JSON of go-merkletree-sql, the hashes are decimal strings
+func newMerkleProof(proof *merkletree.Proof) resources.MerkleProof {
+	allSiblings := proof.AllSiblings()
+	siblings := make([]string, len(allSiblings))
+	for i, sibling := range allSiblings {
+		siblings[i] = sibling.BigInt().String()
+	}
+
+	result := resources.MerkleProof{
+		Existence: proof.Existence,
+		Siblings:  siblings,
+	}
+
+	if proof.NodeAux != nil {
+		result.NodeAux = &resources.MerkleProofNodeAux{
+			Key:   proof.NodeAux.Key.BigInt().String(),
+			Value: proof.NodeAux.Value.BigInt().String(),
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/rarimo/passport-identity-provider/internal/service/api"
+	"github.com/rarimo/passport-identity-provider/internal/service/api/requests"
+	"github.com/rarimo/passport-identity-provider/resources"
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+)
+
+func GetDocumentClaims(w http.ResponseWriter, r *http.Request) {
+	documentHash, err := requests.NewDocumentHashRequest(r)
+	if err != nil {
+		api.Log(r).WithError(err).Error("failed to parse get document claims request")
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
+
+	document, err := api.MasterQ(r).Document().FilterBy("document_hash", documentHash).Get()
+	if err != nil {
+		api.Log(r).WithError(err).Error("failed to get document by hash")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	if document == nil {
+		ape.RenderErr(w, problems.NotFound())
+		return
+	}
+
+	claims, err := api.MasterQ(r).Claim().FilterBy("document_hash", documentHash).Select()
+	if err != nil {
+		api.Log(r).WithError(err).Error("failed to select claims by document hash")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	response := resources.ClaimListResponse{
+		Data:     make([]resources.Claim, len(claims)),
+		Included: resources.Included{},
+	}
+	for i, claim := range claims {
+		response.Data[i] = newDocumentClaim(claim)
+	}
+
+	ape.Render(w, response)
+}
@@ -1,17 +1,16 @@
 package handlers
 
 import (
-	"context"
 	"math/big"
 	"net/http"
 
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/iden3/contracts-abi/state/go/abi"
 	core "github.com/iden3/go-iden3-core/v2"
 	"github.com/iden3/go-iden3-core/v2/w3c"
 	"github.com/rarimo/passport-identity-provider/internal/service/api"
 	"github.com/rarimo/passport-identity-provider/internal/service/api/requests"
+	"github.com/rarimo/passport-identity-provider/internal/service/gist"
 	"github.com/rarimo/passport-identity-provider/resources"
 	"gitlab.com/distributed_lab/ape"
 	"gitlab.com/distributed_lab/ape/problems"
@@ -47,55 +46,28 @@ func GetGistData(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	blockNum, err := api.EthClient(r).BlockNumber(context.Background())
+	gistData, err := api.Gist(r).Get(r.Context(), userID.BigInt(), req.BlockNumber)
 	if err != nil {
-		log.WithError(err).Error("failed to get block number")
+		if errors.Cause(err) == gist.ErrFutureBlock {
+			log.WithError(err).Error("Requested block number is higher than latest")
+			ape.RenderErr(w, problems.BadRequest(validation.Errors{
+				"/block_number": errors.New("Requested block number is higher than latest"),
+			})...)
+			return
+		}
+
+		if cause := errors.Cause(err); cause == gist.ErrInconsistentData || cause == gist.ErrInvalidProof {
+			log.WithError(err).Warn("State contract returned invalid GIST data")
+			renderServiceUnavailable(w, api.Gist(r).RetryAfter())
+			return
+		}
+
+		log.WithError(err).Error("failed to get GIST data")
 		ape.RenderErr(w, problems.InternalError())
 		return
 	}
 
-	if req.BlockNumber > blockNum {
-		log.WithFields(logan.F{
-			"latest_block_number": blockNum,
-		}).Error("Requested block number is higher than latest")
-		ape.RenderErr(w, problems.BadRequest(validation.Errors{
-			"/block_number": errors.New("Requested block number is higher than latest"),
-		})...)
-		return
-	}
-
-	if req.BlockNumber != 0 {
-		blockNum = req.BlockNumber
-	}
-
-	stateContract := api.StateContract(r)
-
-	gistProof, err := stateContract.GetGISTProof(&bind.CallOpts{
-		BlockNumber: new(big.Int).SetUint64(blockNum),
-	}, userID.BigInt())
-	if err != nil {
-		log.WithError(err).Error("failed to get GIST proof")
-		ape.RenderErr(w, problems.InternalError())
-		return
-	}
-
-	gistRoot, err := stateContract.GetGISTRoot(&bind.CallOpts{
-		BlockNumber: new(big.Int).SetUint64(blockNum),
-	})
-	if err != nil {
-		log.WithError(err).Error("failed to get GIST root")
-		ape.RenderErr(w, problems.InternalError())
-		return
-	}
-
-	if gistProof.Root.Cmp(gistRoot) != 0 {
-		log.WithFields(logan.F{
-			"gist_root":       gistRoot.String(),
-			"gist_proof_root": gistProof.Root.String(),
-		}).Warn("gist root does not match")
-	}
-
-	response := newGistDataResponse(req.UserDID, gistProof, gistRoot)
+	response := newGistDataResponse(req.UserDID, gistData.Proof, gistData.Root)
 
 	ape.Render(w, response)
 }
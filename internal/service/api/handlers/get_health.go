@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/google/jsonapi"
+	"github.com/rarimo/passport-identity-provider/internal/service/api"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+	"github.com/rarimo/passport-identity-provider/resources"
+	"gitlab.com/distributed_lab/ape"
+)
+
+// GetHealth reports the state of the service dependencies, it responds with
+// 503 while the circuit breaker of any Issuer Node profile is open
+func GetHealth(w http.ResponseWriter, r *http.Request) {
+	issuers := api.Issuers(r)
+	breakerState := issuers.Default().BreakerState()
+	breakerStates := issuers.BreakerStates()
+
+	healthy := true
+	for _, state := range breakerStates {
+		if state == issuer.BreakerOpen {
+			healthy = false
+		}
+	}
+
+	response := resources.HealthResponse{
+		Data: resources.Health{
+			Key: resources.Key{
+				ID:   "health",
+				Type: resources.HEALTH,
+			},
+			Attributes: resources.HealthAttributes{
+				IssuerBreaker:  breakerState,
+				IssuerBreakers: breakerStates,
+				Healthy:        healthy,
+			},
+		},
+		Included: resources.Included{},
+	}
+
+	if !healthy {
+		w.Header().Set("content-type", jsonapi.MediaType)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	ape.Render(w, response)
+}
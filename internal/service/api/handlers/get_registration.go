@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/api"
+	"github.com/rarimo/passport-identity-provider/internal/service/api/requests"
+	"github.com/rarimo/passport-identity-provider/resources"
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+)
+
+func GetRegistration(w http.ResponseWriter, r *http.Request) {
+	id, err := requests.NewGetRegistrationRequest(r)
+	if err != nil {
+		api.Log(r).WithError(err).Debug("failed to parse get registration request")
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
+
+	masterQ := api.MasterQ(r)
+
+	reg, err := masterQ.Registration().FilterBy("id", id).Get()
+	if err != nil {
+		api.Log(r).WithError(err).Error("failed to get registration")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	if reg == nil {
+		ape.RenderErr(w, problems.NotFound())
+		return
+	}
+
+	response := resources.RegistrationResponse{
+		Data:     newRegistration(*reg),
+		Included: resources.Included{},
+	}
+
+	if reg.ClaimID != nil {
+		claim, err := masterQ.Claim().FilterBy("id", *reg.ClaimID).Get()
+		if err != nil {
+			api.Log(r).WithError(err).Error("failed to get registration claim")
+			ape.RenderErr(w, problems.InternalError())
+			return
+		}
+		if claim != nil {
+			response.Data.Attributes.IssuerDid = &claim.IssuerDID
+		}
+	}
+
+	ape.Render(w, response)
+}
+
+func newRegistration(reg data.Registration) resources.Registration {
+	var claimID *string
+	if reg.ClaimID != nil {
+		id := reg.ClaimID.String()
+		claimID = &id
+	}
+
+	return resources.Registration{
+		Key: resources.Key{
+			ID:   reg.ID.String(),
+			Type: resources.REGISTRATIONS,
+		},
+		Attributes: resources.RegistrationAttributes{
+			Status:        reg.Status,
+			ClaimId:       claimID,
+			FailureCode:   reg.FailureCode,
+			FailureReason: reg.FailureReason,
+			CreatedAt:     reg.CreatedAt,
+			UpdatedAt:     reg.UpdatedAt,
+		},
+	}
+}
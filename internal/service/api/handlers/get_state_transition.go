@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/rarimo/passport-identity-provider/internal/service/api"
+	"github.com/rarimo/passport-identity-provider/internal/service/api/requests"
+	"github.com/rarimo/passport-identity-provider/resources"
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+)
+
+// GetStateTransition returns the state transition of the embedded issuer
+// waiting to be published on-chain with the issuer signature and the auth
+// claim proofs the state transition circuit needs
+func GetStateTransition(w http.ResponseWriter, r *http.Request) {
+	issuerDID, err := requests.NewGetStateTransitionRequest(r)
+	if err != nil {
+		api.Log(r).WithError(err).Error("failed to parse get state transition request")
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
+
+	log := api.Log(r).WithField("issuer_did", issuerDID)
+
+	iss, ok := api.Issuers(r).ByDID(issuerDID)
+	if !ok || !iss.IsEmbedded() {
+		ape.RenderErr(w, problems.NotFound())
+		return
+	}
+
+	transition, err := iss.StateTransition()
+	if err != nil {
+		log.WithError(err).Error("failed to get state transition")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	if transition == nil {
+		ape.RenderErr(w, problems.NotFound())
+		return
+	}
+
+	authClaim, err := transition.AuthClaim.Hex()
+	if err != nil {
+		log.WithError(err).Error("failed to encode auth claim")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	oldState, newState := transition.OldState, transition.NewState
+
+	ape.Render(w, resources.StateTransitionResponse{
+		Data: resources.StateTransition{
+			Key: resources.Key{
+				ID:   newState.State,
+				Type: resources.STATE_TRANSITIONS,
+			},
+			Attributes: resources.StateTransitionAttributes{
+				AuthClaim:            authClaim,
+				AuthClaimMtp:         newMerkleProof(transition.AuthClaimMTP),
+				AuthClaimNonRevProof: newMerkleProof(transition.AuthClaimNonRevProof),
+				ClaimsRoot:           newState.ClaimsRoot,
+				CreatedAt:            newState.CreatedAt,
+				IsOldStateGenesis:    oldState.PreviousState == nil,
+				IssuerDid:            newState.IssuerDID,
+				NewAuthClaimMtp:      newMerkleProof(transition.NewAuthClaimMTP),
+				NewState:             newState.State,
+				OldClaimsRoot:        oldState.ClaimsRoot,
+				OldRevocationRoot:    oldState.RevocationRoot,
+				OldRootsRoot:         oldState.RootsRoot,
+				OldState:             oldState.State,
+				RevocationRoot:       newState.RevocationRoot,
+				RootsRoot:            newState.RootsRoot,
+				Signature: resources.BjjSignature{
+					R8x: transition.Signature.R8.X.String(),
+					R8y: transition.Signature.R8.Y.String(),
+					S:   transition.Signature.S.String(),
+				},
+			},
+		},
+		Included: resources.Included{},
+	})
+}
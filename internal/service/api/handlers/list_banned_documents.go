@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/api"
+	"github.com/rarimo/passport-identity-provider/internal/service/api/requests"
+	"github.com/rarimo/passport-identity-provider/resources"
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+)
+
+func ListBannedDocuments(w http.ResponseWriter, r *http.Request) {
+	req, err := requests.NewListBannedDocumentsRequest(r)
+	if err != nil {
+		api.Log(r).WithError(err).Error("failed to parse list banned documents request")
+		ape.RenderErr(w, problems.BadRequest(err)...)
+		return
+	}
+
+	documents, err := api.MasterQ(r).Document().
+		FilterBy("is_banned", true).
+		Page(&req.OffsetPageParams).
+		Select()
+	if err != nil {
+		api.Log(r).WithError(err).Error("failed to select banned documents")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	response := resources.DocumentListResponse{
+		Data:     make([]resources.Document, len(documents)),
+		Included: resources.Included{},
+	}
+	for i, document := range documents {
+		response.Data[i] = newDocument(document)
+	}
+
+	ape.Render(w, response)
+}
+
+func newDocument(document data.Document) resources.Document {
+	return resources.Document{
+		Key: resources.Key{
+			ID:   document.DocumentHash,
+			Type: resources.DOCUMENTS,
+		},
+		Attributes: resources.DocumentAttributes{
+			RegistrationCount: int64(document.RegistrationCount),
+			IsBanned:          document.IsBanned,
+			BanReason:         document.BanReason,
+			PolicyVersion:     int64(document.PolicyVersion),
+			FirstSeenAt:       document.FirstSeenAt,
+		},
+	}
+}
+
+func newDocumentClaim(claim data.Claim) resources.Claim {
+	return resources.Claim{
+		Key: resources.Key{
+			ID:   claim.ID.String(),
+			Type: resources.CLAIMS,
+		},
+		Attributes: resources.ClaimAttributes{
+			ClaimId:        claim.ID.String(),
+			IssuerDid:      claim.IssuerDID,
+			CredentialType: &claim.CredentialType,
+			UserDid:        &claim.UserDID,
+			CreatedAt:      &claim.CreatedAt,
+			RevokedAt:      claim.RevokedAt,
+			ExpiredAt:      claim.ExpiredAt,
+		},
+	}
+}
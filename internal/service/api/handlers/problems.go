@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/jsonapi"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+	"github.com/rarimo/passport-identity-provider/internal/service/outbox"
+	"github.com/rarimo/passport-identity-provider/internal/service/registration"
+	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/ape/problems"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// renderRegistrationError renders the registration rejections as is, other
+// errors are internal
+func renderRegistrationError(w http.ResponseWriter, log *logan.Entry, err error) {
+	rejection, ok := err.(*registration.Error)
+	if !ok {
+		log.WithError(err).Error("failed to register identity")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	log.WithError(err).Info("identity registration is rejected")
+	if rejection.RetryAfter > 0 {
+		setRetryAfter(w, rejection.RetryAfter)
+	}
+	ape.RenderErr(w, rejection.Problems...)
+}
+
+// renderIssuanceError renders the failure of the recorded issuance. The
+// issuance is retried by the outbox unless it failed permanently, so the
+// client gets the claim by repeating the request once the Issuer Node is
+// available.
+func renderIssuanceError(w http.ResponseWriter, log *logan.Entry, err error, issuance *data.Issuance) {
+	log = log.WithError(err)
+
+	if issuer.IsMisconfiguration(err) {
+		log.Error("issuer node rejected the credential, check the issuer configuration")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	if errors.Cause(err) == outbox.ErrDocumentBanned {
+		log.Info("document was banned before the credential was issued")
+		ape.RenderErr(w, &jsonapi.ErrorObject{
+			Title:  http.StatusText(http.StatusForbidden),
+			Status: fmt.Sprintf("%d", http.StatusForbidden),
+			Code:   registration.CodeDocumentBanned,
+			Detail: "Document is banned",
+		})
+		return
+	}
+
+	if outbox.IsPermanent(err) {
+		log.Error("issuance failed")
+		ape.RenderErr(w, problems.InternalError())
+		return
+	}
+
+	if issuer.IsTemporary(err) {
+		log.Warn("issuer node is not available")
+	} else {
+		log.Error("failed to issue credential")
+	}
+
+	renderServiceUnavailable(w, time.Until(issuance.NextAttemptAt))
+}
+
+func renderServiceUnavailable(w http.ResponseWriter, retryAfter time.Duration) {
+	setRetryAfter(w, retryAfter)
+	ape.RenderErr(w, &jsonapi.ErrorObject{
+		Title:  http.StatusText(http.StatusServiceUnavailable),
+		Status: fmt.Sprintf("%d", http.StatusServiceUnavailable),
+	})
+}
+
+func setRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Round(time.Second).Seconds())))
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/jsonapi"
+	"github.com/rarimo/passport-identity-provider/internal/service/registration"
+	"gitlab.com/distributed_lab/logan/v3"
+)
+
+func TestRenderRegistrationErrorRetryAfter(t *testing.T) {
+	rejection := &registration.Error{
+		Problems: []*jsonapi.ErrorObject{{
+			Title:  http.StatusText(http.StatusServiceUnavailable),
+			Status: fmt.Sprintf("%d", http.StatusServiceUnavailable),
+		}},
+		RetryAfter: 4500 * time.Millisecond,
+	}
+
+	w := httptest.NewRecorder()
+	renderRegistrationError(w, logan.New().Out(io.Discard), rejection)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter != "5" {
+		t.Errorf("expected Retry-After 5, got %q", retryAfter)
+	}
+}
@@ -0,0 +1,36 @@
+package requests
+
+import (
+	"encoding/json"
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/rarimo/passport-identity-provider/resources"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+type BanDocumentRequest struct {
+	DocumentHash string                `json:"-"`
+	Data         resources.DocumentBan `json:"data"`
+}
+
+func NewBanDocumentRequest(r *http.Request) (BanDocumentRequest, error) {
+	var req BanDocumentRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return req, errors.Wrap(err, "failed to unmarshal")
+	}
+
+	documentHash, err := NewDocumentHashRequest(r)
+	if err != nil {
+		return req, err
+	}
+	req.DocumentHash = documentHash
+
+	return req, validation.Errors{
+		"/data/type": validation.Validate(
+			req.Data.Type, validation.Required, validation.In(resources.DOCUMENT_BANS),
+		),
+		"/data/attributes/reason": validation.Validate(req.Data.Attributes.Reason, validation.Required),
+	}.Filter()
+}
@@ -13,16 +13,27 @@ import (
 	"gitlab.com/distributed_lab/logan/v3/errors"
 )
 
+type DocumentSOD struct {
+	SignedAttributes    string `json:"signed_attributes"`
+	Algorithm           string `json:"algorithm"`
+	Signature           string `json:"signature"`
+	PemFile             string `json:"pem_file"`
+	EncapsulatedContent string `json:"encapsulated_content"`
+}
+
 type CreateIdentityRequestData struct {
 	ID          *w3c.DID           `json:"id"`
 	ZKProof     snarkTypes.ZKProof `json:"zkproof"`
-	DocumentSOD struct {
-		SignedAttributes    string `json:"signed_attributes"`
-		Algorithm           string `json:"algorithm"`
-		Signature           string `json:"signature"`
-		PemFile             string `json:"pem_file"`
-		EncapsulatedContent string `json:"encapsulated_content"`
-	} `json:"document_sod"`
+	DocumentSOD DocumentSOD        `json:"document_sod"`
+	// Reregistration moves the document claim to the new DID, the previous
+	// claim is revoked
+	Reregistration bool `json:"reregistration,omitempty"`
+	// Async makes the request return right after the registration is
+	// recorded, its status is polled by the registration ID
+	Async bool `json:"async,omitempty"`
+	// CredentialType is the type of the credential to issue, the issuer
+	// claim type is issued if it is empty
+	CredentialType string `json:"credential_type,omitempty"`
 }
 
 type CreateIdentityRequest struct {
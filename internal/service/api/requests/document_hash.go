@@ -0,0 +1,20 @@
+package requests
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+const documentHashPathParam = "document_hash"
+
+// NewDocumentHashRequest returns the document hash from the request path
+func NewDocumentHashRequest(r *http.Request) (string, error) {
+	documentHash := chi.URLParam(r, documentHashPathParam)
+
+	return documentHash, validation.Errors{
+		"/document_hash": validation.Validate(documentHash, validation.Required, is.Digit),
+	}.Filter()
+}
@@ -0,0 +1,23 @@
+package requests
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+)
+
+const claimIDPathParam = "id"
+
+// NewGetClaimRequest returns the claim ID from the request path
+func NewGetClaimRequest(r *http.Request) (uuid.UUID, error) {
+	id, err := uuid.Parse(chi.URLParam(r, claimIDPathParam))
+	if err != nil {
+		return uuid.Nil, validation.Errors{
+			"/id": err,
+		}
+	}
+
+	return id, nil
+}
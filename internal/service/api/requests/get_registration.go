@@ -0,0 +1,23 @@
+package requests
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/google/uuid"
+)
+
+const registrationIDPathParam = "id"
+
+// NewGetRegistrationRequest returns the registration ID from the request path
+func NewGetRegistrationRequest(r *http.Request) (uuid.UUID, error) {
+	id, err := uuid.Parse(chi.URLParam(r, registrationIDPathParam))
+	if err != nil {
+		return uuid.Nil, validation.Errors{
+			"/id": err,
+		}
+	}
+
+	return id, nil
+}
@@ -0,0 +1,23 @@
+package requests
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/iden3/go-iden3-core/v2/w3c"
+)
+
+const issuerDIDPathParam = "did"
+
+// NewGetStateTransitionRequest returns the issuer DID from the request path
+func NewGetStateTransitionRequest(r *http.Request) (string, error) {
+	did, err := w3c.ParseDID(chi.URLParam(r, issuerDIDPathParam))
+	if err != nil {
+		return "", validation.Errors{
+			"/did": err,
+		}
+	}
+
+	return did.String(), nil
+}
@@ -0,0 +1,30 @@
+package requests
+
+import (
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"gitlab.com/distributed_lab/kit/pgdb"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+	"gitlab.com/distributed_lab/urlval"
+)
+
+const maxPageLimit = 100
+
+type ListBannedDocumentsRequest struct {
+	pgdb.OffsetPageParams
+}
+
+func NewListBannedDocumentsRequest(r *http.Request) (ListBannedDocumentsRequest, error) {
+	var req ListBannedDocumentsRequest
+
+	err := urlval.Decode(r.URL.Query(), &req)
+	if err != nil {
+		return ListBannedDocumentsRequest{}, errors.Wrap(err, "failed to decode url")
+	}
+
+	return req, validation.Errors{
+		"page[limit]": validation.Validate(req.Limit, validation.Max(uint64(maxPageLimit))),
+		"page[order]": validation.Validate(req.Order, validation.In(pgdb.OrderTypeAsc, pgdb.OrderTypeDesc)),
+	}.Filter()
+}
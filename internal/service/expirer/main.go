@@ -0,0 +1,85 @@
+package expirer
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+	"gitlab.com/distributed_lab/running"
+)
+
+// Expirer marks the claims whose credentials expired with the passport, so
+// that they no longer block the DID from registering a renewed passport. The
+// expired claims are revoked by the revoker if it is configured.
+type Expirer struct {
+	log *logan.Entry
+	q   data.MasterQ
+	cfg *config.ExpirerConfig
+}
+
+func New(log *logan.Entry, q data.MasterQ, cfg *config.ExpirerConfig) *Expirer {
+	return &Expirer{
+		log: log,
+		q:   q,
+		cfg: cfg,
+	}
+}
+
+// Run processes the expired claims until ctx is done
+func (e *Expirer) Run(ctx context.Context) {
+	running.WithBackOff(ctx, e.log, "claim-expirer", e.processExpired,
+		e.cfg.Period, e.cfg.Period, e.cfg.Period)
+}
+
+func (e *Expirer) processExpired(_ context.Context) error {
+	now := time.Now().UTC()
+
+	claims, err := e.q.Claim().
+		FilterExpired(now).
+		Limit(e.cfg.BatchSize).
+		Select()
+	if err != nil {
+		return errors.Wrap(err, "failed to select expired claims")
+	}
+
+	if len(claims) == 0 {
+		return nil
+	}
+
+	claimIDs := make([]uuid.UUID, len(claims))
+	for i, claim := range claims {
+		claimIDs[i] = claim.ID
+	}
+
+	err = e.q.Transaction(func(db data.MasterQ) error {
+		if err := db.Claim().FilterBy("id", claimIDs).Update(map[string]any{
+			"expired_at": now,
+		}); err != nil {
+			return errors.Wrap(err, "failed to mark claims as expired")
+		}
+
+		if !e.cfg.Revoke {
+			return nil
+		}
+
+		if err := db.ClaimRevocation().ScheduleForClaims(claimIDs); err != nil {
+			return errors.Wrap(err, "failed to schedule expired claims revocation")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	e.log.WithFields(logan.F{
+		"count":  len(claimIDs),
+		"revoke": e.cfg.Revoke,
+	}).Info("expired claims processed")
+
+	return nil
+}
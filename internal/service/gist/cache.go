@@ -0,0 +1,81 @@
+package gist
+
+import (
+	"container/list"
+	"sync"
+)
+
+type cacheKey struct {
+	userID string
+	block  uint64
+}
+
+type cacheEntry struct {
+	key  cacheKey
+	data Data
+}
+
+// cache is the LRU cache of the GIST data by the user ID and the block, a
+// cache of size 0 stores nothing
+type cache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[cacheKey]*list.Element
+}
+
+func newCache(size int) *cache {
+	return &cache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[cacheKey]*list.Element),
+	}
+}
+
+func (c *cache) get(key cacheKey) (Data, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return Data{}, false
+	}
+
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).data, true
+}
+
+func (c *cache) put(key cacheKey, data Data) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*cacheEntry).data = data
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, data: data})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// dropBlock removes the entries of the block
+func (c *cache) dropBlock(block uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, element := range c.entries {
+		if key.block == block {
+			c.order.Remove(element)
+			delete(c.entries, key)
+		}
+	}
+}
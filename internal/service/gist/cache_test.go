@@ -0,0 +1,276 @@
+package gist
+
+import (
+	"context"
+	"io"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	stateabi "github.com/iden3/contracts-abi/state/go/abi"
+	"github.com/iden3/go-merkletree-sql/v2"
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"gitlab.com/distributed_lab/logan/v3"
+)
+
+// fakeChain is the chain with the GIST of the test tree at every block, the
+// reads of the GIST proofs are recorded
+type fakeChain struct {
+	t *testing.T
+
+	mu     sync.Mutex
+	head   uint64
+	tree   *merkletree.MerkleTree
+	blocks map[common.Hash]uint64
+	// reads are the blocks of the GIST proof reads
+	reads []uint64
+	// gate blocks the GIST proof reads until it is closed, if set
+	gate chan struct{}
+}
+
+func newFakeChain(t *testing.T, head uint64, tree *merkletree.MerkleTree) *fakeChain {
+	return &fakeChain{
+		t:      t,
+		head:   head,
+		tree:   tree,
+		blocks: make(map[common.Hash]uint64),
+	}
+}
+
+// advance moves the head to the block with the GIST of the tree
+func (c *fakeChain) advance(head uint64, tree *merkletree.MerkleTree) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.head = head
+	c.tree = tree
+}
+
+func (c *fakeChain) proofReads() []uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]uint64{}, c.reads...)
+}
+
+func (c *fakeChain) BlockNumber(context.Context) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.head, nil
+}
+
+func (c *fakeChain) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	header := &types.Header{Number: number}
+	c.blocks[header.Hash()] = number.Uint64()
+	return header, nil
+}
+
+func (c *fakeChain) GetGISTProof(opts *bind.CallOpts, id *big.Int) (stateabi.IStateGistProof, error) {
+	c.mu.Lock()
+	c.reads = append(c.reads, c.blocks[opts.BlockHash])
+	tree, gate := c.tree, c.gate
+	c.mu.Unlock()
+
+	if gate != nil {
+		<-gate
+	}
+
+	return gistProof(c.t, tree, id.Int64()), nil
+}
+
+func (c *fakeChain) GetGISTRoot(*bind.CallOpts) (*big.Int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.tree.Root().BigInt(), nil
+}
+
+func newTestProvider(chain *fakeChain, cacheSize int) *Provider {
+	return &Provider{
+		log:   logan.New().Out(io.Discard),
+		eth:   chain,
+		state: chain,
+		cfg: &config.GistConfig{
+			HeadPeriod:     time.Hour,
+			CacheSize:      cacheSize,
+			ReadAttempts:   1,
+			ReadRetryDelay: time.Millisecond,
+		},
+		cache: newCache(cacheSize),
+	}
+}
+
+func mustGet(t *testing.T, p *Provider, userID int64, block uint64) Data {
+	t.Helper()
+
+	data, err := p.Get(context.Background(), big.NewInt(userID), block)
+	if err != nil {
+		t.Fatalf("failed to get GIST data of %d at block %d: %v", userID, block, err)
+	}
+
+	return data
+}
+
+func expectReads(t *testing.T, chain *fakeChain, expected ...uint64) {
+	t.Helper()
+
+	reads := chain.proofReads()
+	if len(reads) != len(expected) {
+		t.Fatalf("expected proof reads at blocks %v, got %v", expected, reads)
+	}
+	for i := range reads {
+		if reads[i] != expected[i] {
+			t.Fatalf("expected proof reads at blocks %v, got %v", expected, reads)
+		}
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	c := newCache(2)
+	c.put(cacheKey{userID: "1", block: 1}, Data{Block: 1})
+	c.put(cacheKey{userID: "2", block: 1}, Data{Block: 1})
+
+	// the first entry is used, so the second one is the oldest
+	c.get(cacheKey{userID: "1", block: 1})
+	c.put(cacheKey{userID: "3", block: 1}, Data{Block: 1})
+
+	if _, ok := c.get(cacheKey{userID: "2", block: 1}); ok {
+		t.Error("expected least recently used entry to be evicted")
+	}
+	for _, userID := range []string{"1", "3"} {
+		if _, ok := c.get(cacheKey{userID: userID, block: 1}); !ok {
+			t.Errorf("expected entry of %s to be kept", userID)
+		}
+	}
+
+	disabled := newCache(0)
+	disabled.put(cacheKey{userID: "1", block: 1}, Data{})
+	if _, ok := disabled.get(cacheKey{userID: "1", block: 1}); ok {
+		t.Error("expected cache of size 0 to store nothing")
+	}
+}
+
+func TestCacheDropBlock(t *testing.T) {
+	c := newCache(10)
+	c.put(cacheKey{userID: "1", block: 1}, Data{Block: 1})
+	c.put(cacheKey{userID: "2", block: 1}, Data{Block: 1})
+	c.put(cacheKey{userID: "1", block: 2}, Data{Block: 2})
+
+	c.dropBlock(1)
+
+	for _, userID := range []string{"1", "2"} {
+		if _, ok := c.get(cacheKey{userID: userID, block: 1}); ok {
+			t.Errorf("expected entry of %s at the dropped block to be removed", userID)
+		}
+	}
+	if _, ok := c.get(cacheKey{userID: "1", block: 2}); !ok {
+		t.Error("expected entry of another block to be kept")
+	}
+	if c.order.Len() != 1 {
+		t.Errorf("expected single entry in the LRU order, got %d", c.order.Len())
+	}
+}
+
+func TestProviderCacheKey(t *testing.T) {
+	chain := newFakeChain(t, 10, newTestGIST(t))
+	p := newTestProvider(chain, 10)
+
+	// the root is first seen at block 10, the later blocks with the same
+	// root share its entries
+	mustGet(t, p, 1, 0)
+	chain.advance(15, chain.tree)
+	if data := mustGet(t, p, 1, 15); data.Block != 10 {
+		t.Fatalf("expected data of root block 10, got %d", data.Block)
+	}
+	mustGet(t, p, 1, 12)
+	mustGet(t, p, 1, 0)
+	expectReads(t, chain, 10)
+
+	// the entries are per user
+	mustGet(t, p, 3, 12)
+	expectReads(t, chain, 10, 10)
+
+	// the root of the blocks before the root block is not known, they are
+	// read at the requested block
+	mustGet(t, p, 1, 5)
+	mustGet(t, p, 1, 5)
+	expectReads(t, chain, 10, 10, 5)
+}
+
+func TestProviderDropsBlockOnRootChange(t *testing.T) {
+	tree := newTestGIST(t)
+	chain := newFakeChain(t, 10, tree)
+	p := newTestProvider(chain, 10)
+
+	oldData := mustGet(t, p, 1, 0)
+
+	changed := newTestGIST(t)
+	if err := changed.Add(context.Background(), big.NewInt(2), big.NewInt(200)); err != nil {
+		t.Fatal(err)
+	}
+	chain.advance(20, changed)
+
+	// the newer block is past the known head, so the head is refreshed
+	newData := mustGet(t, p, 1, 20)
+	if newData.Block != 20 {
+		t.Fatalf("expected data of the new root block 20, got %d", newData.Block)
+	}
+	if newData.Root.Cmp(oldData.Root) == 0 {
+		t.Fatal("expected data of the new root")
+	}
+	expectReads(t, chain, 10, 20)
+
+	if _, ok := p.cache.get(cacheKey{userID: "1", block: 10}); ok {
+		t.Fatal("expected entries of the old root block to be dropped")
+	}
+
+	mustGet(t, p, 1, 0)
+	expectReads(t, chain, 10, 20)
+}
+
+func TestProviderSharesConcurrentReads(t *testing.T) {
+	chain := newFakeChain(t, 10, newTestGIST(t))
+	// the cache stores nothing, so only the concurrent requests share the
+	// read
+	p := newTestProvider(chain, 0)
+	mustGet(t, p, 1, 0)
+
+	gate := make(chan struct{})
+	chain.mu.Lock()
+	chain.gate = gate
+	chain.mu.Unlock()
+
+	const requests = 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := p.Get(context.Background(), big.NewInt(1), 0); err != nil {
+				errs <- err
+			}
+		}()
+	}
+
+	// the requests wait for the first read until the gate is closed
+	time.Sleep(100 * time.Millisecond)
+	close(gate)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("failed to get GIST data: %v", err)
+	}
+
+	expectReads(t, chain, 10, 10)
+}
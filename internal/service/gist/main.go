@@ -0,0 +1,265 @@
+package gist
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	stateabi "github.com/iden3/contracts-abi/state/go/abi"
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+	"gitlab.com/distributed_lab/running"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrFutureBlock is returned if the requested block is higher than the
+// latest one
+var ErrFutureBlock = errors.New("requested block number is higher than latest")
+
+// ErrInconsistentData is returned if the GIST proof root does not match the
+// GIST root read at the same block after all the read attempts
+var ErrInconsistentData = errors.New("gist proof root does not match gist root")
+
+// Data is the GIST proof of the user and the GIST root at the block
+type Data struct {
+	Proof stateabi.IStateGistProof
+	Root  *big.Int
+	Block uint64
+}
+
+// chainReader is the part of the Ethereum client the blocks are read with
+type chainReader interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// gistCaller is the part of the State contract the GIST data is read from
+type gistCaller interface {
+	GetGISTProof(opts *bind.CallOpts, id *big.Int) (stateabi.IStateGistProof, error)
+	GetGISTRoot(opts *bind.CallOpts) (*big.Int, error)
+}
+
+// Provider reads the GIST data from the State contract. The data is cached
+// by the user ID and the block. The latest block and its GIST root are read
+// periodically, the requests for any block since the root was first seen
+// share the cache entries of that block, as the root and so the proofs did
+// not change since. The entries are dropped when the root changes.
+type Provider struct {
+	log   *logan.Entry
+	eth   chainReader
+	state gistCaller
+	cfg   *config.GistConfig
+	cache *cache
+	group singleflight.Group
+
+	mu          sync.RWMutex
+	head        uint64
+	root        *big.Int
+	rootBlock   uint64
+	refreshedAt time.Time
+}
+
+func New(log *logan.Entry, eth *ethclient.Client, state *stateabi.State, cfg *config.GistConfig) *Provider {
+	return &Provider{
+		log:   log,
+		eth:   eth,
+		state: state,
+		cfg:   cfg,
+		cache: newCache(cfg.CacheSize),
+	}
+}
+
+// Run reads the latest block and its GIST root until ctx is done
+func (p *Provider) Run(ctx context.Context) {
+	running.WithBackOff(ctx, p.log, "gist-head", p.refreshHead,
+		p.cfg.HeadPeriod, p.cfg.HeadPeriod, 10*p.cfg.HeadPeriod)
+}
+
+// Get returns the GIST data of the user at the block, at the latest block if
+// it is 0
+func (p *Provider) Get(ctx context.Context, userID *big.Int, blockNumber uint64) (Data, error) {
+	head, root, rootBlock, fresh := p.latest()
+	// the block may be newer than the one seen by the last refresh
+	if !fresh || blockNumber > head {
+		if err := p.refreshHead(ctx); err != nil {
+			return Data{}, errors.Wrap(err, "failed to refresh latest block")
+		}
+		head, root, rootBlock, _ = p.latest()
+	}
+
+	if blockNumber > head {
+		return Data{}, errors.From(ErrFutureBlock, logan.F{
+			"latest_block_number": head,
+		})
+	}
+
+	key := cacheKey{userID: userID.String(), block: blockNumber}
+	if blockNumber == 0 || blockNumber >= rootBlock {
+		key.block = rootBlock
+	} else {
+		// the root of the older blocks is not known
+		root = nil
+	}
+
+	if data, ok := p.cache.get(key); ok {
+		return data, nil
+	}
+
+	// the concurrent requests of the same data share the RPC calls, so the
+	// calls are not canceled with the request that made them
+	result, err, _ := p.group.Do(fmt.Sprintf("%s:%d", key.userID, key.block), func() (interface{}, error) {
+		data, err := p.fetch(context.WithoutCancel(ctx), userID, key.block)
+		if err != nil {
+			return nil, err
+		}
+
+		// the root of the block differs from the one seen by the refresh
+		// after a reorg, the entry would outlive the root
+		if root == nil || root.Cmp(data.Root) == 0 {
+			p.cache.put(key, data)
+		}
+		return data, nil
+	})
+	if err != nil {
+		return Data{}, err
+	}
+
+	return result.(Data), nil
+}
+
+// RetryAfter returns the delay before the request failed with
+// ErrInconsistentData or ErrInvalidProof may be repeated
+func (p *Provider) RetryAfter() time.Duration {
+	return p.cfg.HeadPeriod
+}
+
+// fetch reads the GIST proof of the user and the GIST root at the block. Both
+// are read at the block hash, so the calls can not be served at different
+// blocks. The proof is verified against its root, the reads are repeated if
+// it is invalid or the proof root still does not match.
+func (p *Provider) fetch(ctx context.Context, userID *big.Int, block uint64) (Data, error) {
+	for attempt := 1; ; attempt++ {
+		data, err := p.read(ctx, userID, block)
+		if err != nil {
+			return Data{}, err
+		}
+
+		fields := logan.F{
+			"block_number":    block,
+			"attempt":         attempt,
+			"gist_root":       data.Root.String(),
+			"gist_proof_root": data.Proof.Root.String(),
+		}
+
+		var cause error
+		if err = verifyProof(userID, data.Proof); err != nil {
+			cause = ErrInvalidProof
+			fields["reason"] = err.Error()
+		} else if data.Proof.Root.Cmp(data.Root) != 0 {
+			cause = ErrInconsistentData
+		} else {
+			return data, nil
+		}
+
+		if attempt >= p.cfg.ReadAttempts {
+			return Data{}, errors.From(cause, fields)
+		}
+		p.log.WithFields(fields).WithError(cause).Warn("invalid gist data, retrying")
+
+		select {
+		case <-ctx.Done():
+			return Data{}, ctx.Err()
+		case <-time.After(p.cfg.ReadRetryDelay):
+		}
+	}
+}
+
+func (p *Provider) read(ctx context.Context, userID *big.Int, block uint64) (Data, error) {
+	header, err := p.eth.HeaderByNumber(ctx, new(big.Int).SetUint64(block))
+	if err != nil {
+		return Data{}, errors.Wrap(err, "failed to get block header")
+	}
+
+	opts := &bind.CallOpts{
+		Context:   ctx,
+		BlockHash: header.Hash(),
+	}
+
+	proof, err := p.state.GetGISTProof(opts, userID)
+	if err != nil {
+		return Data{}, errors.Wrap(err, "failed to get GIST proof")
+	}
+
+	root, err := p.state.GetGISTRoot(opts)
+	if err != nil {
+		return Data{}, errors.Wrap(err, "failed to get GIST root")
+	}
+
+	return Data{
+		Proof: proof,
+		Root:  root,
+		Block: block,
+	}, nil
+}
+
+// latest returns the latest block, its GIST root and the block the root was
+// first seen at, fresh is false if the block was not refreshed recently
+func (p *Provider) latest() (head uint64, root *big.Int, rootBlock uint64, fresh bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	fresh = p.root != nil && time.Since(p.refreshedAt) < 2*p.cfg.HeadPeriod
+	return p.head, p.root, p.rootBlock, fresh
+}
+
+func (p *Provider) refreshHead(ctx context.Context) error {
+	head, err := p.eth.BlockNumber(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get block number")
+	}
+
+	p.mu.RLock()
+	known := head <= p.head
+	p.mu.RUnlock()
+
+	if known {
+		p.mu.Lock()
+		p.refreshedAt = time.Now()
+		p.mu.Unlock()
+		return nil
+	}
+
+	root, err := p.state.GetGISTRoot(&bind.CallOpts{
+		Context:     ctx,
+		BlockNumber: new(big.Int).SetUint64(head),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to get GIST root")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// a concurrent refresh has seen a newer block
+	if head <= p.head {
+		return nil
+	}
+
+	if p.root == nil || p.root.Cmp(root) != 0 {
+		if p.root != nil {
+			p.cache.dropBlock(p.rootBlock)
+		}
+		p.root = root
+		p.rootBlock = head
+	}
+	p.head = head
+	p.refreshedAt = time.Now()
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package gist
+
+import (
+	"math/big"
+
+	stateabi "github.com/iden3/contracts-abi/state/go/abi"
+	"github.com/iden3/go-merkletree-sql/v2"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// ErrInvalidProof is returned if the GIST proof read from the State contract
+// does not lead to its root after all the read attempts
+var ErrInvalidProof = errors.New("gist proof does not lead to gist root")
+
+// verifyProof checks that the GIST proof of the user leads to the proof root.
+// The State contract builds the GIST as the go-merkletree-sql tree, so the
+// proof is converted to the tree one and verified by go-merkletree-sql.
+func verifyProof(userID *big.Int, proof stateabi.IStateGistProof) error {
+	if proof.Index.Cmp(userID) != 0 {
+		return errors.New("proof index does not match user ID")
+	}
+
+	siblings := make([]*merkletree.Hash, len(proof.Siblings))
+	for i, sibling := range proof.Siblings {
+		hash, err := merkletree.NewHashFromBigInt(sibling)
+		if err != nil {
+			return errors.Wrap(err, "invalid sibling", logan.F{"level": i})
+		}
+		siblings[i] = hash
+	}
+
+	var nodeAux *merkletree.NodeAux
+	if !proof.Existence && proof.AuxExistence {
+		key, err := merkletree.NewHashFromBigInt(proof.AuxIndex)
+		if err != nil {
+			return errors.Wrap(err, "invalid auxiliary leaf index")
+		}
+
+		value, err := merkletree.NewHashFromBigInt(proof.AuxValue)
+		if err != nil {
+			return errors.Wrap(err, "invalid auxiliary leaf value")
+		}
+
+		nodeAux = &merkletree.NodeAux{Key: key, Value: value}
+	}
+
+	treeProof, err := merkletree.NewProofFromData(proof.Existence, siblings, nodeAux)
+	if err != nil {
+		return errors.Wrap(err, "failed to build tree proof")
+	}
+
+	root, err := merkletree.NewHashFromBigInt(proof.Root)
+	if err != nil {
+		return errors.Wrap(err, "invalid proof root")
+	}
+
+	value := big.NewInt(0)
+	if proof.Existence {
+		value = proof.Value
+	}
+
+	if !merkletree.VerifyProof(root, treeProof, proof.Index, value) {
+		return errors.New("proof does not lead to proof root")
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package gist
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	stateabi "github.com/iden3/contracts-abi/state/go/abi"
+	"github.com/iden3/go-merkletree-sql/v2"
+	"github.com/iden3/go-merkletree-sql/v2/db/memory"
+)
+
+// gistLevels is the depth of the State contract GIST
+const gistLevels = 64
+
+// newTestGIST builds the tree the same way the State contract builds the GIST.
+// The index path is read from the least significant bit: 1 and 3 share the
+// right subtree of the root and diverge at the second level, 2 goes to the
+// empty left subtree of the root and 5 ends at the leaf of 1.
+func newTestGIST(t *testing.T) *merkletree.MerkleTree {
+	t.Helper()
+
+	tree, err := merkletree.NewMerkleTree(context.Background(), memory.NewMemoryStorage(), gistLevels)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, index := range []int64{1, 3} {
+		if err := tree.Add(context.Background(), big.NewInt(index), big.NewInt(index*100)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return tree
+}
+
+// gistProof converts the tree proof to the one returned by the State contract
+func gistProof(t *testing.T, tree *merkletree.MerkleTree, index int64) stateabi.IStateGistProof {
+	t.Helper()
+
+	proof, value, err := tree.GenerateProof(context.Background(), big.NewInt(index), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := stateabi.IStateGistProof{
+		Root:      tree.Root().BigInt(),
+		Existence: proof.Existence,
+		Index:     big.NewInt(index),
+		Value:     big.NewInt(0),
+		AuxIndex:  big.NewInt(0),
+		AuxValue:  big.NewInt(0),
+	}
+	if proof.Existence {
+		result.Value = value
+	}
+	if proof.NodeAux != nil {
+		result.AuxExistence = true
+		result.AuxIndex = proof.NodeAux.Key.BigInt()
+		result.AuxValue = proof.NodeAux.Value.BigInt()
+	}
+
+	for i := range result.Siblings {
+		result.Siblings[i] = big.NewInt(0)
+	}
+	for i, sibling := range proof.AllSiblings() {
+		result.Siblings[i] = sibling.BigInt()
+	}
+
+	return result
+}
+
+func TestVerifyProof(t *testing.T) {
+	tree := newTestGIST(t)
+
+	tests := []struct {
+		name    string
+		userID  int64
+		proof   func() stateabi.IStateGistProof
+		wantErr bool
+	}{
+		{
+			name:   "existence",
+			userID: 3,
+			proof:  func() stateabi.IStateGistProof { return gistProof(t, tree, 3) },
+		},
+		{
+			name:   "non-existence with auxiliary leaf",
+			userID: 5,
+			proof: func() stateabi.IStateGistProof {
+				proof := gistProof(t, tree, 5)
+				if !proof.AuxExistence {
+					t.Fatal("expected proof with auxiliary leaf")
+				}
+				return proof
+			},
+		},
+		{
+			name:   "non-existence ending in empty node",
+			userID: 2,
+			proof: func() stateabi.IStateGistProof {
+				proof := gistProof(t, tree, 2)
+				if proof.Existence || proof.AuxExistence {
+					t.Fatal("expected proof ending in empty node")
+				}
+				return proof
+			},
+		},
+		{
+			name:   "tampered sibling",
+			userID: 3,
+			proof: func() stateabi.IStateGistProof {
+				proof := gistProof(t, tree, 3)
+				proof.Siblings[1] = new(big.Int).Add(proof.Siblings[1], big.NewInt(1))
+				return proof
+			},
+			wantErr: true,
+		},
+		{
+			name:   "padding sibling made non-empty",
+			userID: 3,
+			proof: func() stateabi.IStateGistProof {
+				proof := gistProof(t, tree, 3)
+				proof.Siblings[2] = big.NewInt(1)
+				return proof
+			},
+			wantErr: true,
+		},
+		{
+			name:   "tampered value",
+			userID: 3,
+			proof: func() stateabi.IStateGistProof {
+				proof := gistProof(t, tree, 3)
+				proof.Value = big.NewInt(301)
+				return proof
+			},
+			wantErr: true,
+		},
+		{
+			name:    "mismatched index",
+			userID:  1,
+			proof:   func() stateabi.IStateGistProof { return gistProof(t, tree, 3) },
+			wantErr: true,
+		},
+		{
+			name:   "auxiliary leaf of the user",
+			userID: 3,
+			proof: func() stateabi.IStateGistProof {
+				proof := gistProof(t, tree, 3)
+				proof.Existence = false
+				proof.AuxExistence = true
+				proof.AuxIndex = proof.Index
+				proof.AuxValue = proof.Value
+				return proof
+			},
+			wantErr: true,
+		},
+		{
+			name:   "existence claimed for absent user",
+			userID: 5,
+			proof: func() stateabi.IStateGistProof {
+				proof := gistProof(t, tree, 5)
+				proof.Existence = true
+				proof.Value = proof.AuxValue
+				return proof
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyProof(big.NewInt(tt.userID), tt.proof())
+			if tt.wantErr && err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		})
+	}
+}
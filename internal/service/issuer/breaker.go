@@ -0,0 +1,108 @@
+package issuer
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// breaker stops the calls to the Issuer Node after threshold consecutive
+// failures. Once openTimeout is over a single probe call is let through, it
+// closes the breaker on success or opens it again on failure.
+type breaker struct {
+	mu          sync.Mutex
+	threshold   int
+	openTimeout time.Duration
+	state       string
+	failures    int
+	openedAt    time.Time
+	probing     bool
+}
+
+func newBreaker(threshold int, openTimeout time.Duration) *breaker {
+	return &breaker{
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		state:       BreakerClosed,
+	}
+}
+
+// allow reports whether the call can be made, every allowed call must be
+// followed by done
+func (b *breaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.openTimeout {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *breaker) done(success bool) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+func (b *breaker) currentState() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// retryAfter is the time left until the open breaker lets a probe call
+// through
+func (b *breaker) retryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != BreakerOpen {
+		return 0
+	}
+
+	left := b.openTimeout - time.Since(b.openedAt)
+	if left < 0 {
+		return 0
+	}
+	return left
+}
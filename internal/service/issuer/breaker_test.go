@@ -0,0 +1,165 @@
+package issuer
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testOpenTimeout = time.Minute
+
+// openBreaker returns the breaker opened by threshold failed calls
+func openBreaker(t *testing.T, threshold int) *breaker {
+	t.Helper()
+
+	b := newBreaker(threshold, testOpenTimeout)
+	for i := 0; i < threshold; i++ {
+		if !b.allow() {
+			t.Fatalf("call %d: expected call to be allowed", i)
+		}
+		b.done(false)
+	}
+
+	if state := b.currentState(); state != BreakerOpen {
+		t.Fatalf("expected breaker to be %s, got %s", BreakerOpen, state)
+	}
+
+	return b
+}
+
+// expireOpenTimeout moves the opening of the breaker back, so that its open
+// timeout is over
+func expireOpenTimeout(b *breaker) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.openedAt = time.Now().Add(-testOpenTimeout)
+}
+
+func TestBreakerOpensAtThreshold(t *testing.T) {
+	b := newBreaker(3, testOpenTimeout)
+
+	for i := 0; i < 2; i++ {
+		if !b.allow() {
+			t.Fatalf("call %d: expected call to be allowed", i)
+		}
+		b.done(false)
+	}
+	if state := b.currentState(); state != BreakerClosed {
+		t.Fatalf("expected breaker to be %s below threshold, got %s", BreakerClosed, state)
+	}
+
+	// a success resets the consecutive failures
+	b.allow()
+	b.done(true)
+	for i := 0; i < 2; i++ {
+		b.allow()
+		b.done(false)
+	}
+	if state := b.currentState(); state != BreakerClosed {
+		t.Fatalf("expected breaker to be %s after success, got %s", BreakerClosed, state)
+	}
+
+	b.allow()
+	b.done(false)
+	if state := b.currentState(); state != BreakerOpen {
+		t.Fatalf("expected breaker to be %s at threshold, got %s", BreakerOpen, state)
+	}
+}
+
+func TestBreakerRejectsWhileOpen(t *testing.T) {
+	b := openBreaker(t, 2)
+
+	for i := 0; i < 10; i++ {
+		if b.allow() {
+			t.Fatalf("call %d: expected call to be rejected", i)
+		}
+	}
+
+	if retryAfter := b.retryAfter(); retryAfter <= 0 || retryAfter > testOpenTimeout {
+		t.Errorf("expected retry after in (0, %s], got %s", testOpenTimeout, retryAfter)
+	}
+}
+
+func TestBreakerSingleProbe(t *testing.T) {
+	b := openBreaker(t, 2)
+	expireOpenTimeout(b)
+
+	if retryAfter := b.retryAfter(); retryAfter != 0 {
+		t.Errorf("expected no retry after once open timeout is over, got %s", retryAfter)
+	}
+
+	const calls = 50
+
+	var (
+		wg      sync.WaitGroup
+		allowed atomic.Int32
+	)
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if b.allow() {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed.Load() != 1 {
+		t.Fatalf("expected single probe call, got %d", allowed.Load())
+	}
+
+	if state := b.currentState(); state != BreakerHalfOpen {
+		t.Fatalf("expected breaker to be %s while probing, got %s", BreakerHalfOpen, state)
+	}
+}
+
+func TestBreakerProbeResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		success  bool
+		expected string
+		allowed  bool
+	}{
+		{"successful probe closes", true, BreakerClosed, true},
+		{"failed probe reopens", false, BreakerOpen, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := openBreaker(t, 2)
+			expireOpenTimeout(b)
+
+			if !b.allow() {
+				t.Fatal("expected probe call to be allowed")
+			}
+			b.done(tt.success)
+
+			if state := b.currentState(); state != tt.expected {
+				t.Fatalf("expected breaker to be %s, got %s", tt.expected, state)
+			}
+
+			// the reopened breaker waits the whole open timeout again
+			if allowed := b.allow(); allowed != tt.allowed {
+				t.Fatalf("expected call allowed %t, got %t", tt.allowed, allowed)
+			}
+		})
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := newBreaker(0, testOpenTimeout)
+
+	for i := 0; i < 10; i++ {
+		if !b.allow() {
+			t.Fatalf("call %d: expected call to be allowed", i)
+		}
+		b.done(false)
+	}
+
+	if state := b.currentState(); state != BreakerClosed {
+		t.Fatalf("expected disabled breaker to be %s, got %s", BreakerClosed, state)
+	}
+}
@@ -0,0 +1,191 @@
+package issuer
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	core "github.com/iden3/go-iden3-core/v2"
+	"github.com/iden3/go-iden3-core/v2/w3c"
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// issueEmbedded builds the claim of the credential and issues it with the
+// embedded identity
+func (is *Issuer) issueEmbedded(
+	template config.CredentialTemplate, subjectDID string, subject map[string]any,
+	credentialData CredentialData, expiration *time.Time,
+) (string, error) {
+	claim, err := buildClaim(template, subjectDID, credentialData, expiration)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to build claim", logan.F{
+			"credential_type": template.Type,
+		})
+	}
+
+	rawSubject, err := json.Marshal(subject)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal credential subject")
+	}
+
+	schemaHash := claim.GetSchemaHash()
+	credential := data.EmbeddedCredential{
+		ID:                uuid.New(),
+		UserDID:           subjectDID,
+		CredentialType:    template.Type,
+		SchemaURL:         template.Schema,
+		SchemaHash:        hex.EncodeToString(schemaHash[:]),
+		CredentialSubject: string(rawSubject),
+		DocumentNullifier: credentialData.DocumentNullifier.String(),
+		Expiration:        expiration,
+	}
+
+	if err := is.identity.Issue(claim, credential); err != nil {
+		return "", errors.Wrap(err, "failed to issue embedded credential")
+	}
+
+	return credential.ID.String(), nil
+}
+
+func (is *Issuer) getEmbedded(claimID uuid.UUID) (GetCredentialResponse, error) {
+	credential, published, err := is.identity.Credential(claimID)
+	if err != nil {
+		return GetCredentialResponse{}, errors.Wrap(err, "failed to get embedded credential")
+	}
+
+	if credential == nil {
+		return GetCredentialResponse{}, errors.From(ErrNotFound, logan.F{"credential_id": claimID.String()})
+	}
+
+	return embeddedCredentialResponse(*credential, published), nil
+}
+
+func (is *Issuer) findEmbedded(
+	template config.CredentialTemplate, subjectDID, nullifier string,
+) (*GetCredentialResponse, error) {
+	credential, err := is.identity.FindCredential(template.Type, subjectDID, nullifier)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find embedded credential")
+	}
+
+	if credential == nil {
+		return nil, nil
+	}
+
+	// the state of a credential found by the outbox retry is not needed
+	response := embeddedCredentialResponse(*credential, false)
+	return &response, nil
+}
+
+func embeddedCredentialResponse(credential data.EmbeddedCredential, published bool) GetCredentialResponse {
+	response := GetCredentialResponse{
+		Id:                credential.ID.String(),
+		ProofTypes:        []string{ProofTypeSignature},
+		CreatedAt:         credential.CreatedAt,
+		SchemaHash:        credential.SchemaHash,
+		SchemaType:        credential.CredentialType,
+		SchemaUrl:         credential.SchemaURL,
+		Revoked:           credential.Revoked,
+		RevNonce:          credential.RevocationNonce,
+		CredentialSubject: json.RawMessage(credential.CredentialSubject),
+		UserID:            credential.UserDID,
+	}
+
+	if published {
+		response.ProofTypes = append(response.ProofTypes, ProofTypeMTP)
+	}
+
+	if credential.Expiration != nil {
+		response.ExpiresAt = *credential.Expiration
+		response.Expired = credential.Expiration.Before(time.Now())
+	}
+
+	return response
+}
+
+// buildClaim builds the iden3 claim of the credential, the document data is
+// put into the slots listed by the template and the subject ID is the index
+// ID
+func buildClaim(
+	template config.CredentialTemplate, subjectDID string, data CredentialData, expiration *time.Time,
+) (*core.Claim, error) {
+	did, err := w3c.ParseDID(subjectDID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse subject DID")
+	}
+
+	subjectID, err := core.IDFromDID(*did)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get subject ID")
+	}
+
+	indexSlots, err := slotValues(template, data, template.IndexSlots)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build index slots")
+	}
+
+	valueSlots, err := slotValues(template, data, template.ValueSlots)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build value slots")
+	}
+
+	revocationNonce, err := newRevocationNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	options := []core.Option{
+		core.WithIndexID(subjectID),
+		core.WithRevocationNonce(revocationNonce),
+		core.WithIndexDataInts(indexSlots[0], indexSlots[1]),
+		core.WithValueDataInts(valueSlots[0], valueSlots[1]),
+	}
+	if expiration != nil {
+		options = append(options, core.WithExpirationDate(*expiration))
+	}
+
+	return core.NewClaim(schemaHash(template), options...)
+}
+
+// schemaHash is the last 16 bytes of the Keccak256 hash of the credential
+// type ID in the JSON-LD context, the same as in the Issuer Node
+func schemaHash(template config.CredentialTemplate) core.SchemaHash {
+	var result core.SchemaHash
+
+	hash := crypto.Keccak256([]byte(template.Context + "#" + template.Type))
+	copy(result[:], hash[len(hash)-len(result):])
+
+	return result
+}
+
+func slotValues(template config.CredentialTemplate, data CredentialData, fields []string) ([2]*big.Int, error) {
+	var result [2]*big.Int
+	for i, field := range fields {
+		value, err := fieldValue(template, data, field)
+		if err != nil {
+			return result, err
+		}
+		result[i] = value
+	}
+
+	return result, nil
+}
+
+// newRevocationNonce generates a random nonce fitting int64, the nonces are
+// stored and returned as signed integers
+func newRevocationNonce() (uint64, error) {
+	var raw [8]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return 0, errors.Wrap(err, "failed to generate revocation nonce")
+	}
+
+	return binary.BigEndian.Uint64(raw[:]) >> 1, nil
+}
@@ -0,0 +1,455 @@
+package embedded
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/google/uuid"
+	stateabi "github.com/iden3/contracts-abi/state/go/abi"
+	core "github.com/iden3/go-iden3-core/v2"
+	"github.com/iden3/go-iden3-core/v2/w3c"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"github.com/iden3/go-iden3-crypto/poseidon"
+	"github.com/iden3/go-merkletree-sql/v2"
+	"github.com/iden3/go-merkletree-sql/v2/db/memory"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+	"gitlab.com/distributed_lab/running"
+)
+
+// authSchemaHash is the schema hash of the AuthBJJCredential claim holding
+// the issuer public key
+const authSchemaHash = "ca938857241db9451ea329256b9c06e5"
+
+// ErrDIDMismatch is returned if the configured DID is not the genesis DID of
+// the issuer key
+var ErrDIDMismatch = errors.New("issuer DID does not match the key")
+
+// Identity is the iden3 identity of the embedded issuer. The claims and
+// revocation trees are changed when the credentials are issued and revoked,
+// the changes are collected into the state transitions periodically.
+type Identity struct {
+	log       *logan.Entry
+	q         data.MasterQ
+	state     *stateabi.State
+	did       string
+	id        core.ID
+	key       babyjub.PrivateKey
+	authClaim *core.Claim
+	treeIDs   *treeIDs
+	period    time.Duration
+}
+
+// New creates the identity of the key and records its genesis state unless
+// it is recorded already. The key must be the one the DID was created with.
+// The published states are read from the State contract.
+func New(
+	log *logan.Entry,
+	q data.MasterQ,
+	state *stateabi.State,
+	did *w3c.DID,
+	key babyjub.PrivateKey,
+	transitionPeriod time.Duration,
+) (*Identity, error) {
+	id, err := core.IDFromDID(*did)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get ID from DID")
+	}
+
+	authClaim, err := newAuthClaim(key)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		log:       log,
+		q:         q,
+		state:     state,
+		did:       did.String(),
+		id:        id,
+		key:       key,
+		authClaim: authClaim,
+		period:    transitionPeriod,
+	}
+
+	if err := identity.checkDID(); err != nil {
+		return nil, err
+	}
+
+	identity.treeIDs, err = newTreeIDs(q.New().MerkleTree(), identity.did)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get identity trees")
+	}
+
+	if err := identity.init(); err != nil {
+		return nil, errors.Wrap(err, "failed to init identity")
+	}
+
+	return identity, nil
+}
+
+// newAuthClaim builds the AuthBJJCredential claim of the key with revocation
+// nonce 0
+func newAuthClaim(key babyjub.PrivateKey) (*core.Claim, error) {
+	schemaHash, err := core.NewSchemaHashFromHex(authSchemaHash)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse auth schema hash")
+	}
+
+	publicKey := key.Public()
+	authClaim, err := core.NewClaim(schemaHash,
+		core.WithIndexDataInts(publicKey.X, publicKey.Y),
+		core.WithRevocationNonce(0),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build auth claim")
+	}
+
+	return authClaim, nil
+}
+
+func (i *Identity) DID() string {
+	return i.did
+}
+
+// checkDID compares the DID with the one derived from the genesis state of
+// the tree holding only the auth claim
+func (i *Identity) checkDID() error {
+	hIndex, hValue, err := i.authClaim.HiHv()
+	if err != nil {
+		return errors.Wrap(err, "failed to hash auth claim")
+	}
+
+	claims, err := merkletree.NewMerkleTree(context.Background(), memory.NewMemoryStorage(), treeLevels)
+	if err != nil {
+		return errors.Wrap(err, "failed to create genesis claims tree")
+	}
+
+	if err := claims.Add(context.Background(), hIndex, hValue); err != nil {
+		return errors.Wrap(err, "failed to add auth claim to genesis claims tree")
+	}
+
+	state, err := core.IdenState(claims.Root().BigInt(), big.NewInt(0), big.NewInt(0))
+	if err != nil {
+		return errors.Wrap(err, "failed to build genesis state")
+	}
+
+	genesisID, err := core.NewIDFromIdenState(i.id.Type(), state)
+	if err != nil {
+		return errors.Wrap(err, "failed to build genesis ID")
+	}
+
+	if !genesisID.Equal(&i.id) {
+		genesisDID, err := core.ParseDIDFromID(*genesisID)
+		if err != nil {
+			return errors.Wrap(err, "failed to build genesis DID")
+		}
+
+		return errors.From(ErrDIDMismatch, logan.F{
+			"did":         i.did,
+			"genesis_did": genesisDID.String(),
+		})
+	}
+
+	return nil
+}
+
+// init adds the auth claim to the empty claims tree and records the genesis
+// state, which does not need to be published
+func (i *Identity) init() error {
+	return i.q.New().Transaction(func(db data.MasterQ) error {
+		if err := db.IdentityState().Lock(i.did); err != nil {
+			return errors.Wrap(err, "failed to lock identity")
+		}
+
+		genesis, err := db.IdentityState().FilterBy("issuer_did", i.did).Last()
+		if err != nil {
+			return errors.Wrap(err, "failed to get last identity state")
+		}
+
+		if genesis != nil {
+			return nil
+		}
+
+		hIndex, hValue, err := i.authClaim.HiHv()
+		if err != nil {
+			return errors.Wrap(err, "failed to hash auth claim")
+		}
+
+		trees, err := i.trees(context.Background(), db)
+		if err != nil {
+			return err
+		}
+
+		if err := trees.claims.Add(context.Background(), hIndex, hValue); err != nil {
+			return errors.Wrap(err, "failed to add auth claim")
+		}
+
+		state, err := trees.state(i.did)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		state.PublishedAt = &now
+
+		if err := db.IdentityState().Insert(*state); err != nil {
+			return errors.Wrap(err, "failed to insert genesis state")
+		}
+
+		i.log.WithField("state", state.State).Info("genesis state recorded")
+		return nil
+	})
+}
+
+// Issue adds the claim to the claims tree and stores the credential with the
+// claim signature
+func (i *Identity) Issue(claim *core.Claim, credential data.EmbeddedCredential) error {
+	hIndex, hValue, err := claim.HiHv()
+	if err != nil {
+		return errors.Wrap(err, "failed to hash claim")
+	}
+
+	claimHash, err := poseidon.Hash([]*big.Int{hIndex, hValue})
+	if err != nil {
+		return errors.Wrap(err, "failed to build claim hash")
+	}
+
+	claimHex, err := claim.Hex()
+	if err != nil {
+		return errors.Wrap(err, "failed to encode claim")
+	}
+
+	credential.IssuerDID = i.did
+	credential.Claim = claimHex
+	credential.Signature = i.key.SignPoseidon(claimHash).Compress().String()
+	credential.RevocationNonce = int64(claim.GetRevocationNonce())
+
+	return i.q.New().Transaction(func(db data.MasterQ) error {
+		if err := db.IdentityState().Lock(i.did); err != nil {
+			return errors.Wrap(err, "failed to lock identity")
+		}
+
+		trees, err := i.trees(context.Background(), db)
+		if err != nil {
+			return err
+		}
+
+		if err := trees.claims.Add(context.Background(), hIndex, hValue); err != nil {
+			return errors.Wrap(err, "failed to add claim to claims tree")
+		}
+
+		if err := db.EmbeddedCredential().Insert(credential); err != nil {
+			return errors.Wrap(err, "failed to insert credential")
+		}
+
+		return nil
+	})
+}
+
+// Credential returns the credential by its ID and whether the state including
+// its claim is published, nil if there is no credential
+func (i *Identity) Credential(id uuid.UUID) (*data.EmbeddedCredential, bool, error) {
+	db := i.q.New()
+
+	credential, err := db.EmbeddedCredential().
+		FilterBy("id", id).
+		FilterBy("issuer_did", i.did).
+		Get()
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to get credential")
+	}
+
+	if credential == nil || credential.State == nil {
+		return credential, false, nil
+	}
+
+	state, err := db.IdentityState().
+		FilterBy("issuer_did", i.did).
+		FilterBy("state", *credential.State).
+		Last()
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to get credential state")
+	}
+
+	return credential, state != nil && state.PublishedAt != nil, nil
+}
+
+// FindCredential returns the credential of the type issued to the subject
+// with the document nullifier, nil if there is none
+func (i *Identity) FindCredential(credentialType, subjectDID, nullifier string) (*data.EmbeddedCredential, error) {
+	credential, err := i.q.New().EmbeddedCredential().
+		FilterBy("issuer_did", i.did).
+		FilterBy("user_did", subjectDID).
+		FilterBy("credential_type", credentialType).
+		FilterBy("document_nullifier", nullifier).
+		Get()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get credential")
+	}
+
+	return credential, nil
+}
+
+// Revoke adds the revocation nonce to the revocation tree, the nonces that
+// are already revoked are skipped
+func (i *Identity) Revoke(revocationNonce int64) error {
+	return i.q.New().Transaction(func(db data.MasterQ) error {
+		if err := db.IdentityState().Lock(i.did); err != nil {
+			return errors.Wrap(err, "failed to lock identity")
+		}
+
+		trees, err := i.trees(context.Background(), db)
+		if err != nil {
+			return err
+		}
+
+		err = trees.revocations.Add(context.Background(), big.NewInt(revocationNonce), big.NewInt(0))
+		if err != nil && errors.Cause(err) != merkletree.ErrEntryIndexAlreadyExists {
+			return errors.Wrap(err, "failed to add nonce to revocation tree")
+		}
+
+		err = db.EmbeddedCredential().
+			FilterBy("issuer_did", i.did).
+			FilterBy("revocation_nonce", revocationNonce).
+			Update(map[string]any{"revoked": true})
+		if err != nil {
+			return errors.Wrap(err, "failed to mark credential as revoked")
+		}
+
+		return nil
+	})
+}
+
+// Run records the state transitions until ctx is done
+func (i *Identity) Run(ctx context.Context) {
+	running.WithBackOff(ctx, i.log, "state-transitions", i.transit,
+		i.period, i.period, i.period)
+}
+
+// transit records the transition to the state of the current trees. The
+// transitions are published one by one, so the next one is recorded only
+// after the last one is published.
+func (i *Identity) transit(ctx context.Context) error {
+	if err := i.markPublished(ctx); err != nil {
+		return errors.Wrap(err, "failed to mark published state")
+	}
+
+	return i.q.New().Transaction(func(db data.MasterQ) error {
+		if err := db.IdentityState().Lock(i.did); err != nil {
+			return errors.Wrap(err, "failed to lock identity")
+		}
+
+		last, err := db.IdentityState().FilterBy("issuer_did", i.did).Last()
+		if err != nil {
+			return errors.Wrap(err, "failed to get last identity state")
+		}
+
+		if last == nil {
+			return errors.New("genesis state is missing")
+		}
+
+		if last.PublishedAt == nil {
+			return nil
+		}
+
+		trees, err := i.trees(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		claimsRoot := trees.claims.Root().BigInt()
+		revocationRoot := trees.revocations.Root().BigInt()
+		if claimsRoot.String() == last.ClaimsRoot && revocationRoot.String() == last.RevocationRoot {
+			return nil
+		}
+
+		// the roots tree holds the claims roots of the published states, a
+		// state only with revocations keeps the previous claims root
+		err = trees.roots.Add(ctx, claimsRoot, big.NewInt(0))
+		if err != nil && errors.Cause(err) != merkletree.ErrEntryIndexAlreadyExists {
+			return errors.Wrap(err, "failed to add claims root to roots tree")
+		}
+
+		state, err := trees.state(i.did)
+		if err != nil {
+			return err
+		}
+		state.PreviousState = &last.State
+
+		if err := db.IdentityState().Insert(*state); err != nil {
+			return errors.Wrap(err, "failed to insert identity state")
+		}
+
+		err = db.EmbeddedCredential().
+			FilterBy("issuer_did", i.did).
+			FilterBy("state", nil).
+			Update(map[string]any{"state": state.State})
+		if err != nil {
+			return errors.Wrap(err, "failed to set credentials state")
+		}
+
+		i.log.WithFields(logan.F{
+			"old_state": last.State,
+			"new_state": state.State,
+		}).Info("state transition recorded")
+
+		return nil
+	})
+}
+
+// markPublished sets published_at of the last state to the time it was
+// published on-chain once it is the issuer state in the State contract
+func (i *Identity) markPublished(ctx context.Context) error {
+	db := i.q.New()
+
+	last, err := db.IdentityState().FilterBy("issuer_did", i.did).Last()
+	if err != nil {
+		return errors.Wrap(err, "failed to get last identity state")
+	}
+
+	if last == nil || last.PublishedAt != nil {
+		return nil
+	}
+
+	opts := &bind.CallOpts{Context: ctx}
+
+	// the genesis state is published implicitly, so the issuer is missing in
+	// the contract until its first transition is published
+	exists, err := i.state.IdExists(opts, i.id.BigInt())
+	if err != nil {
+		return errors.Wrap(err, "failed to check whether issuer state exists")
+	}
+
+	if !exists {
+		return nil
+	}
+
+	info, err := i.state.GetStateInfoById(opts, i.id.BigInt())
+	if err != nil {
+		return errors.Wrap(err, "failed to get issuer state info")
+	}
+
+	if info.State.String() != last.State {
+		return nil
+	}
+
+	publishedAt := time.Unix(info.CreatedAtTimestamp.Int64(), 0).UTC()
+	err = db.IdentityState().
+		FilterBy("id", last.ID).
+		FilterBy("published_at", nil).
+		Update(map[string]any{"published_at": publishedAt})
+	if err != nil {
+		return errors.Wrap(err, "failed to mark identity state as published")
+	}
+
+	i.log.WithFields(logan.F{
+		"state":        last.State,
+		"block_number": info.CreatedAtBlock.Uint64(),
+	}).Info("state transition published")
+
+	return nil
+}
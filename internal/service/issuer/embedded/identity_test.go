@@ -0,0 +1,91 @@
+package embedded
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	core "github.com/iden3/go-iden3-core/v2"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"github.com/iden3/go-merkletree-sql/v2"
+	"github.com/iden3/go-merkletree-sql/v2/db/memory"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// newTestIdentity returns the identity of the key with the given ID, only the
+// fields needed without the database are set
+func newTestIdentity(t *testing.T, key babyjub.PrivateKey, id core.ID) *Identity {
+	t.Helper()
+
+	authClaim, err := newAuthClaim(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	did, err := core.ParseDIDFromID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Identity{
+		did:       did.String(),
+		id:        id,
+		key:       key,
+		authClaim: authClaim,
+	}
+}
+
+// genesisID builds the ID of the key the same way the Issuer Node does
+func genesisID(t *testing.T, key babyjub.PrivateKey) core.ID {
+	t.Helper()
+
+	authClaim, err := newAuthClaim(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := merkletree.NewMerkleTree(context.Background(), memory.NewMemoryStorage(), treeLevels)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hIndex, hValue, err := authClaim.HiHv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := claims.Add(context.Background(), hIndex, hValue); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := merkletree.HashElems(claims.Root().BigInt(), big.NewInt(0), big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	typ, err := core.BuildDIDType(core.DIDMethodIden3, core.Polygon, core.Mumbai)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := core.NewIDFromIdenState(typ, state.BigInt())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return *id
+}
+
+func TestCheckDID(t *testing.T) {
+	key := babyjub.NewRandPrivKey()
+	otherKey := babyjub.NewRandPrivKey()
+
+	if err := newTestIdentity(t, key, genesisID(t, key)).checkDID(); err != nil {
+		t.Fatalf("expected genesis DID of the key to match, got %v", err)
+	}
+
+	err := newTestIdentity(t, key, genesisID(t, otherKey)).checkDID()
+	if errors.Cause(err) != ErrDIDMismatch {
+		t.Fatalf("expected %v, got %v", ErrDIDMismatch, err)
+	}
+}
@@ -0,0 +1,289 @@
+package embedded
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/google/uuid"
+	core "github.com/iden3/go-iden3-core/v2"
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"github.com/iden3/go-iden3-crypto/poseidon"
+	"github.com/iden3/go-merkletree-sql/v2"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// ClaimProof is the signed claim of the credential with its proofs against
+// the latest published state of the issuer, the same data the Issuer Node
+// puts into the BJJ signature and the Merkle tree proofs of a credential
+type ClaimProof struct {
+	Credential data.EmbeddedCredential
+	// State is the latest published state the proofs are built against
+	State data.IdentityState
+	// AuthClaim is the auth claim of the key the claim is signed with
+	AuthClaim            *core.Claim
+	AuthClaimMTP         *merkletree.Proof
+	AuthClaimNonRevProof *merkletree.Proof
+	// MTP is nil until the claim is included in the published state
+	MTP         *merkletree.Proof
+	NonRevProof *merkletree.Proof
+}
+
+// ClaimProof returns the signed claim of the credential with its proofs, nil
+// if there is no credential
+func (i *Identity) ClaimProof(id uuid.UUID) (*ClaimProof, error) {
+	db := i.q.New()
+
+	credential, err := db.EmbeddedCredential().
+		FilterBy("id", id).
+		FilterBy("issuer_did", i.did).
+		Get()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get credential")
+	}
+
+	if credential == nil {
+		return nil, nil
+	}
+
+	var claim core.Claim
+	if err := claim.FromHex(credential.Claim); err != nil {
+		return nil, errors.Wrap(err, "failed to decode claim")
+	}
+
+	state, err := i.publishedState(db)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	trees, err := i.trees(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	authClaimMTP, authClaimNonRevProof, err := i.authClaimProofs(ctx, trees, *state)
+	if err != nil {
+		return nil, err
+	}
+
+	result := ClaimProof{
+		Credential:           *credential,
+		State:                *state,
+		AuthClaim:            i.authClaim,
+		AuthClaimMTP:         authClaimMTP,
+		AuthClaimNonRevProof: authClaimNonRevProof,
+	}
+
+	hIndex, err := claim.HIndex()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to hash claim index")
+	}
+
+	mtp, err := generateProof(ctx, trees.claims, hIndex, state.ClaimsRoot)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate claim proof")
+	}
+
+	if mtp.Existence {
+		result.MTP = mtp
+	}
+
+	result.NonRevProof, err = generateProof(ctx, trees.revocations,
+		new(big.Int).SetUint64(claim.GetRevocationNonce()), state.RevocationRoot)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate claim non-revocation proof")
+	}
+
+	return &result, nil
+}
+
+// publishedState returns the latest published state of the issuer. Only the
+// last state may wait to be published, so otherwise it is the previous one.
+func (i *Identity) publishedState(db data.MasterQ) (*data.IdentityState, error) {
+	last, err := db.IdentityState().FilterBy("issuer_did", i.did).Last()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get last identity state")
+	}
+
+	if last == nil {
+		return nil, errors.New("genesis state is missing")
+	}
+
+	if last.PublishedAt != nil {
+		return last, nil
+	}
+
+	if last.PreviousState == nil {
+		return nil, errors.From(errors.New("unpublished state has no previous one"), logan.F{
+			"state": last.State,
+		})
+	}
+
+	previous, err := db.IdentityState().
+		FilterBy("issuer_did", i.did).
+		FilterBy("state", *last.PreviousState).
+		Last()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get previous identity state")
+	}
+
+	if previous == nil {
+		return nil, errors.From(errors.New("previous identity state is missing"), logan.F{
+			"state": last.State,
+		})
+	}
+
+	return previous, nil
+}
+
+// authClaimProofs returns the proofs that the auth claim is in the claims
+// tree and is not revoked in the state
+func (i *Identity) authClaimProofs(
+	ctx context.Context, trees *trees, state data.IdentityState,
+) (mtp, nonRevProof *merkletree.Proof, err error) {
+	hIndex, err := i.authClaim.HIndex()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to hash auth claim index")
+	}
+
+	mtp, err = generateProof(ctx, trees.claims, hIndex, state.ClaimsRoot)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate auth claim proof")
+	}
+
+	nonRevProof, err = generateProof(ctx, trees.revocations,
+		new(big.Int).SetUint64(i.authClaim.GetRevocationNonce()), state.RevocationRoot)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to generate auth claim non-revocation proof")
+	}
+
+	return mtp, nonRevProof, nil
+}
+
+// generateProof returns the proof of the key in the tree with the root, the
+// nodes are never removed from the storage, so the roots of the previous
+// states are still available
+func generateProof(
+	ctx context.Context, tree *merkletree.MerkleTree, key *big.Int, root string,
+) (*merkletree.Proof, error) {
+	rootHash, err := merkletree.NewHashFromString(root)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse tree root", logan.F{"root": root})
+	}
+
+	proof, _, err := tree.GenerateProof(ctx, key, rootHash)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate proof", logan.F{"root": root})
+	}
+
+	return proof, nil
+}
+
+// StateTransition is the pending transition of the issuer with the inputs of
+// the state transition circuit that are known only to the issuer
+type StateTransition struct {
+	OldState  data.IdentityState
+	NewState  data.IdentityState
+	AuthClaim *core.Claim
+	// AuthClaimMTP and AuthClaimNonRevProof are built against the old state
+	AuthClaimMTP         *merkletree.Proof
+	AuthClaimNonRevProof *merkletree.Proof
+	// NewAuthClaimMTP is built against the new state
+	NewAuthClaimMTP *merkletree.Proof
+	// Signature is the signature of the Poseidon hash of the old and new
+	// states
+	Signature *babyjub.Signature
+}
+
+// StateTransition returns the transition waiting to be published, nil if
+// there is none
+func (i *Identity) StateTransition() (*StateTransition, error) {
+	db := i.q.New()
+
+	// the genesis state is published when it is recorded, so the pending
+	// state always has the previous one
+	newState, err := db.IdentityState().
+		FilterBy("issuer_did", i.did).
+		FilterBy("published_at", nil).
+		Last()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get pending identity state")
+	}
+
+	if newState == nil || newState.PreviousState == nil {
+		return nil, nil
+	}
+
+	oldState, err := db.IdentityState().
+		FilterBy("issuer_did", i.did).
+		FilterBy("state", *newState.PreviousState).
+		Last()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get previous identity state")
+	}
+
+	if oldState == nil {
+		return nil, errors.From(errors.New("previous identity state is missing"), logan.F{
+			"state": newState.State,
+		})
+	}
+
+	ctx := context.Background()
+	trees, err := i.trees(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	authClaimMTP, authClaimNonRevProof, err := i.authClaimProofs(ctx, trees, *oldState)
+	if err != nil {
+		return nil, err
+	}
+
+	hIndex, err := i.authClaim.HIndex()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to hash auth claim index")
+	}
+
+	newAuthClaimMTP, err := generateProof(ctx, trees.claims, hIndex, newState.ClaimsRoot)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate new auth claim proof")
+	}
+
+	signature, err := i.signTransition(oldState.State, newState.State)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StateTransition{
+		OldState:             *oldState,
+		NewState:             *newState,
+		AuthClaim:            i.authClaim,
+		AuthClaimMTP:         authClaimMTP,
+		AuthClaimNonRevProof: authClaimNonRevProof,
+		NewAuthClaimMTP:      newAuthClaimMTP,
+		Signature:            signature,
+	}, nil
+}
+
+// signTransition signs the Poseidon hash of the old and new states, the
+// message the state transition circuit checks
+func (i *Identity) signTransition(oldState, newState string) (*babyjub.Signature, error) {
+	oldInt, ok := new(big.Int).SetString(oldState, 10)
+	if !ok {
+		return nil, errors.From(errors.New("invalid old state"), logan.F{"state": oldState})
+	}
+
+	newInt, ok := new(big.Int).SetString(newState, 10)
+	if !ok {
+		return nil, errors.From(errors.New("invalid new state"), logan.F{"state": newState})
+	}
+
+	hash, err := poseidon.Hash([]*big.Int{oldInt, newInt})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to hash state transition")
+	}
+
+	return i.key.SignPoseidon(hash), nil
+}
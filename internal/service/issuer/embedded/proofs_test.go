@@ -0,0 +1,91 @@
+package embedded
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"github.com/iden3/go-iden3-crypto/poseidon"
+	"github.com/iden3/go-merkletree-sql/v2"
+	"github.com/iden3/go-merkletree-sql/v2/db/memory"
+)
+
+func TestGenerateProofOfPreviousRoot(t *testing.T) {
+	ctx := context.Background()
+
+	tree, err := merkletree.NewMerkleTree(ctx, memory.NewMemoryStorage(), treeLevels)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	add := func(key int64) {
+		if err := tree.Add(ctx, big.NewInt(key), big.NewInt(key+100)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	add(1)
+	add(2)
+	previousRoot := tree.Root()
+	add(3)
+	add(4)
+
+	tests := []struct {
+		name      string
+		key       int64
+		existence bool
+	}{
+		{"key of previous root", 1, true},
+		{"key added after previous root", 3, false},
+		{"missing key", 5, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proof, err := generateProof(ctx, tree, big.NewInt(tt.key), previousRoot.BigInt().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if proof.Existence != tt.existence {
+				t.Fatalf("expected existence %t, got %t", tt.existence, proof.Existence)
+			}
+
+			value := big.NewInt(0)
+			if tt.existence {
+				value = big.NewInt(tt.key + 100)
+			}
+			if !merkletree.VerifyProof(previousRoot, proof, big.NewInt(tt.key), value) {
+				t.Fatal("expected proof to verify against previous root")
+			}
+		})
+	}
+
+	if _, err := generateProof(ctx, tree, big.NewInt(1), "root"); err == nil {
+		t.Fatal("expected invalid root to fail")
+	}
+}
+
+func TestSignTransition(t *testing.T) {
+	key := babyjub.NewRandPrivKey()
+	identity := &Identity{key: key}
+
+	signature, err := identity.signTransition("1", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := poseidon.Hash([]*big.Int{big.NewInt(1), big.NewInt(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !key.Public().VerifyPoseidon(hash, signature) {
+		t.Fatal("expected signature of the transition hash to verify")
+	}
+
+	if _, err := identity.signTransition("state", "2"); err == nil {
+		t.Fatal("expected invalid state to fail")
+	}
+}
@@ -0,0 +1,105 @@
+package embedded
+
+import (
+	"context"
+
+	core "github.com/iden3/go-iden3-core/v2"
+	"github.com/iden3/go-merkletree-sql/v2"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// treeLevels is the depth of the identity trees, the same as in the Issuer
+// Node and the iden3 circuits
+const treeLevels = 40
+
+// treeIDs are the IDs of the identity trees in the storage
+type treeIDs struct {
+	claims      int64
+	revocations int64
+	roots       int64
+}
+
+func newTreeIDs(q data.MerkleTreeQ, issuerDID string) (*treeIDs, error) {
+	claims, err := q.TreeID(issuerDID, data.MerkleTreeClaims)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get claims tree ID")
+	}
+
+	revocations, err := q.TreeID(issuerDID, data.MerkleTreeRevocations)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get revocation tree ID")
+	}
+
+	roots, err := q.TreeID(issuerDID, data.MerkleTreeRoots)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get roots tree ID")
+	}
+
+	return &treeIDs{
+		claims:      claims,
+		revocations: revocations,
+		roots:       roots,
+	}, nil
+}
+
+// trees are the identity trees loaded with the queries, the trees are changed
+// in the transaction of the queries
+type trees struct {
+	claims      *merkletree.MerkleTree
+	revocations *merkletree.MerkleTree
+	roots       *merkletree.MerkleTree
+}
+
+func (i *Identity) trees(ctx context.Context, db data.MasterQ) (*trees, error) {
+	claims, err := loadTree(ctx, db, i.treeIDs.claims)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load claims tree")
+	}
+
+	revocations, err := loadTree(ctx, db, i.treeIDs.revocations)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load revocation tree")
+	}
+
+	roots, err := loadTree(ctx, db, i.treeIDs.roots)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load roots tree")
+	}
+
+	return &trees{
+		claims:      claims,
+		revocations: revocations,
+		roots:       roots,
+	}, nil
+}
+
+func loadTree(ctx context.Context, db data.MasterQ, treeID int64) (*merkletree.MerkleTree, error) {
+	tree, err := merkletree.NewMerkleTree(ctx, db.MerkleTree().Storage(treeID), treeLevels)
+	if err != nil {
+		return nil, errors.From(err, logan.F{"tree_id": treeID})
+	}
+
+	return tree, nil
+}
+
+// state builds the identity state of the current tree roots
+func (t *trees) state(issuerDID string) (*data.IdentityState, error) {
+	claimsRoot := t.claims.Root().BigInt()
+	revocationRoot := t.revocations.Root().BigInt()
+	rootsRoot := t.roots.Root().BigInt()
+
+	state, err := core.IdenState(claimsRoot, revocationRoot, rootsRoot)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build identity state")
+	}
+
+	return &data.IdentityState{
+		IssuerDID:      issuerDID,
+		State:          state.String(),
+		ClaimsRoot:     claimsRoot.String(),
+		RevocationRoot: revocationRoot.String(),
+		RootsRoot:      rootsRoot.String(),
+	}, nil
+}
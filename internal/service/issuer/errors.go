@@ -0,0 +1,112 @@
+package issuer
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+
+	"github.com/imroc/req/v3"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+var (
+	// ErrUnexpectedStatusCode is returned for the Issuer Node responses that
+	// are not mapped to the errors below, including the server errors
+	ErrUnexpectedStatusCode = errors.New("unexpected status code")
+	// ErrUnavailable is returned without calling the Issuer Node while the
+	// circuit breaker is open
+	ErrUnavailable = errors.New("issuer node is unavailable")
+	// ErrSchemaInvalid is returned if the credential does not match the
+	// configured credential schema
+	ErrSchemaInvalid = errors.New("credential schema is invalid")
+	// ErrDIDNotFound is returned if the Issuer Node does not know the DID or
+	// can not parse it
+	ErrDIDNotFound = errors.New("did is not found")
+	// ErrUnauthorized is returned if the Issuer Node rejects the configured
+	// credentials
+	ErrUnauthorized = errors.New("issuer node authorization failed")
+	// ErrRateLimited is returned if the Issuer Node limits the request rate
+	ErrRateLimited = errors.New("issuer node rate limit exceeded")
+	// ErrNotFound is returned if the requested credential does not exist
+	ErrNotFound = errors.New("credential is not found")
+	// ErrBadRequest is returned for the rest of the rejected requests
+	ErrBadRequest = errors.New("issuer node rejected the request")
+)
+
+var (
+	schemaMessage = regexp.MustCompile(`(?i)\bschema\b`)
+	didMessage    = regexp.MustCompile(`(?i)\b(did|identity)\b`)
+)
+
+// ErrorResponse is the error payload of the Issuer Node
+type ErrorResponse struct {
+	Message string `json:"message"`
+}
+
+// IsMisconfiguration reports whether the error is caused by the service
+// configuration rather than by the Issuer Node availability
+func IsMisconfiguration(err error) bool {
+	switch errors.Cause(err) {
+	case ErrSchemaInvalid, ErrDIDNotFound, ErrUnauthorized:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsTemporary reports whether the call failed because the Issuer Node can not
+// serve it right now and can be repeated later
+func IsTemporary(err error) bool {
+	switch errors.Cause(err) {
+	case ErrUnavailable, ErrRateLimited:
+		return true
+	default:
+		return false
+	}
+}
+
+// responseError maps the Issuer Node error response to one of the errors
+// above, the response message and status code are attached as fields
+func responseError(response *req.Response) error {
+	message := response.String()
+
+	var payload ErrorResponse
+	if err := json.Unmarshal(response.Bytes(), &payload); err == nil && payload.Message != "" {
+		message = payload.Message
+	}
+
+	fields := logan.F{
+		"status_code": response.StatusCode,
+		"message":     message,
+	}
+
+	return errors.From(statusError(response.StatusCode, message), fields)
+}
+
+// statusError maps the response by its status code. The Issuer Node error
+// payload has no error code, only the message, so the message is used only to
+// tell the schema and DID rejections among the generic bad requests, and it
+// is matched by whole words.
+func statusError(statusCode int, message string) error {
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode == http.StatusNotFound:
+		return ErrNotFound
+	case statusCode == http.StatusUnprocessableEntity:
+		return ErrSchemaInvalid
+	case statusCode >= http.StatusInternalServerError:
+		return ErrUnexpectedStatusCode
+	case statusCode == http.StatusBadRequest && schemaMessage.MatchString(message):
+		return ErrSchemaInvalid
+	case statusCode == http.StatusBadRequest && didMessage.MatchString(message):
+		return ErrDIDNotFound
+	case statusCode >= http.StatusBadRequest:
+		return ErrBadRequest
+	default:
+		return ErrUnexpectedStatusCode
+	}
+}
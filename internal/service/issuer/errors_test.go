@@ -0,0 +1,36 @@
+package issuer
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		message    string
+		expected   error
+	}{
+		{"not found mentioning identity", http.StatusNotFound, "identity credential not found", ErrNotFound},
+		{"unprocessable mentioning did", http.StatusUnprocessableEntity, "did field is invalid", ErrSchemaInvalid},
+		{"server error mentioning schema", http.StatusInternalServerError, "failed to load schema", ErrUnexpectedStatusCode},
+		{"unauthorized", http.StatusUnauthorized, "", ErrUnauthorized},
+		{"rate limited", http.StatusTooManyRequests, "", ErrRateLimited},
+		{"bad request schema", http.StatusBadRequest, "Schema validation failed", ErrSchemaInvalid},
+		{"bad request did", http.StatusBadRequest, "invalid DID", ErrDIDNotFound},
+		{"bad request word containing did", http.StatusBadRequest, "didcomm message is rejected", ErrBadRequest},
+		{"bad request word containing schema", http.StatusBadRequest, "schemaless request", ErrBadRequest},
+		{"bad request", http.StatusBadRequest, "expiration is in the past", ErrBadRequest},
+		{"conflict", http.StatusConflict, "identity exists", ErrBadRequest},
+		{"redirect", http.StatusFound, "", ErrUnexpectedStatusCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := statusError(tt.statusCode, tt.message); err != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
@@ -1,14 +1,15 @@
 package issuer
 
 import (
-	"github.com/google/uuid"
-	"math/big"
+	"encoding/json"
+	"net/http"
 	"strconv"
 	"time"
 
-	"github.com/iden3/go-iden3-crypto/poseidon"
+	"github.com/google/uuid"
 	"github.com/imroc/req/v3"
 	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuer/embedded"
 	"gitlab.com/distributed_lab/logan/v3"
 	"gitlab.com/distributed_lab/logan/v3/errors"
 )
@@ -16,98 +17,352 @@ import (
 type Issuer struct {
 	log    *logan.Entry
 	client *req.Client
-	cfg    *config.IssuerConfig
-	did    string
+	// coreClient calls the Issuer Node core API
+	coreClient *req.Client
+	cfg        *config.IssuerConfig
+	did        string
+	breaker    *breaker
+	templates  map[string]config.CredentialTemplate
+	// identity is set in the embedded mode, the credentials are issued by it
+	// instead of the Issuer Node
+	identity *embedded.Identity
 }
 
-func New(log *logan.Entry, config *config.IssuerConfig, login, password string) *Issuer {
+// New creates the Issuer Node client issuing the credentials of the given
+// templates. The voting credential template is built from the issuer
+// claim_type and credential_schema unless a template of that type is given.
+func New(
+	log *logan.Entry, cfg *config.IssuerConfig, credentials *config.CredentialsConfig, login, password string,
+) *Issuer {
 	return &Issuer{
+		log: log,
 		client: req.C().
-			SetBaseURL(config.BaseUrl).
+			SetBaseURL(cfg.BaseUrl).
+			SetCommonBasicAuth(login, password).
+			SetTimeout(cfg.Timeout).
+			SetLogger(log),
+		coreClient: req.C().
+			SetBaseURL(cfg.CoreBaseUrl).
 			SetCommonBasicAuth(login, password).
+			SetTimeout(cfg.Timeout).
 			SetLogger(log),
-		cfg: config,
-		did: config.DID.String(),
+		cfg:       cfg,
+		did:       cfg.DID.String(),
+		breaker:   newBreaker(cfg.BreakerThreshold, cfg.BreakerOpenTimeout),
+		templates: newTemplates(cfg, credentials),
+	}
+}
+
+// NewEmbedded creates the issuer issuing the credentials with the embedded
+// identity, the Issuer Node is not called. Every credential template must
+// have the context the claim schema hash is derived from.
+func NewEmbedded(
+	log *logan.Entry, cfg *config.IssuerConfig, credentials *config.CredentialsConfig, identity *embedded.Identity,
+) (*Issuer, error) {
+	templates := newTemplates(cfg, credentials)
+	for _, template := range templates {
+		if template.Context == "" {
+			return nil, errors.From(errors.New("credential context is required by the embedded issuer"), logan.F{
+				"credential_type": template.Type,
+			})
+		}
+	}
+
+	return &Issuer{
+		log:       log,
+		cfg:       cfg,
+		did:       identity.DID(),
+		breaker:   newBreaker(0, 0),
+		templates: templates,
+		identity:  identity,
+	}, nil
+}
+
+func newTemplates(cfg *config.IssuerConfig, credentials *config.CredentialsConfig) map[string]config.CredentialTemplate {
+	templates := make(map[string]config.CredentialTemplate, len(credentials.Templates)+1)
+	for _, template := range credentials.Templates {
+		templates[template.Type] = template
+	}
+	if _, ok := templates[cfg.ClaimType]; !ok {
+		templates[cfg.ClaimType] = config.VotingCredentialTemplate(cfg.ClaimType, cfg.CredentialSchema, cfg.CredentialContext)
+	}
+
+	return templates
+}
+
+// BreakerState is the state of the Issuer Node circuit breaker
+func (is *Issuer) BreakerState() string {
+	return is.breaker.currentState()
+}
+
+// RetryAfter is the time left until the calls rejected with ErrUnavailable
+// are tried again
+func (is *Issuer) RetryAfter() time.Duration {
+	return is.breaker.retryAfter()
+}
+
+// do sends the request through the circuit breaker, network errors and 5xx
+// responses are counted as failures
+func (is *Issuer) do(send func() (*req.Response, error)) (*req.Response, error) {
+	if !is.breaker.allow() {
+		return nil, ErrUnavailable
+	}
+
+	response, err := send()
+	is.breaker.done(err == nil && response.StatusCode < http.StatusInternalServerError)
+	if err == nil && response.StatusCode >= http.StatusInternalServerError {
+		is.log.WithField("status_code", response.StatusCode).Warn("issuer node responded with server error")
 	}
+
+	return response, err
+}
+
+// idempotent configures the retries of the request that can be safely
+// repeated
+func (is *Issuer) idempotent(r *req.Request) *req.Request {
+	return r.
+		SetRetryCount(is.cfg.RetryCount).
+		SetRetryBackoffInterval(is.cfg.RetryMinBackoff, is.cfg.RetryMaxBackoff).
+		AddRetryCondition(func(response *req.Response, err error) bool {
+			return err != nil || response.StatusCode >= http.StatusInternalServerError
+		})
 }
 
 func (is *Issuer) DID() string {
 	return is.did
 }
 
-func (is *Issuer) IssueVotingClaim(
-	id string, issuingAuthority int64, isAdult bool, expiration *time.Time, nullifier *big.Int,
+// ClaimType is the credential type issued when the request does not set one
+func (is *Issuer) ClaimType() string {
+	return is.cfg.ClaimType
+}
+
+// Template returns the template of the credential type, false if the type is
+// not configured
+func (is *Issuer) Template(credentialType string) (config.CredentialTemplate, bool) {
+	template, ok := is.templates[credentialType]
+	return template, ok
+}
+
+// IssueCredential creates the credential of the template for the subject,
+// the credential ID is returned
+func (is *Issuer) IssueCredential(
+	template config.CredentialTemplate, subjectDID string, data CredentialData, expiration *time.Time,
 ) (string, error) {
 	var result UUIDResponse
 
-	credHashInput := make([]*big.Int, 0)
-	credHashInput = append(credHashInput, big.NewInt(1))
-	credHashInput = append(credHashInput, big.NewInt(issuingAuthority))
-	credHashInput = append(credHashInput, nullifier)
-
-	credentialHash, err := poseidon.Hash(credHashInput)
+	subject, err := credentialSubject(template, subjectDID, data)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to hash bytes")
+		return "", errors.Wrap(err, "failed to build credential subject", logan.F{
+			"credential_type": template.Type,
+		})
+	}
+
+	if is.identity != nil {
+		return is.issueEmbedded(template, subjectDID, subject, data, expiration)
 	}
 
 	credentialRequest := CredentialRequest{
-		CredentialSchema: is.cfg.CredentialSchema,
-		Type:             is.cfg.ClaimType,
-		CredentialSubject: CredentialSubject{
-			ID:                id,
-			IssuingAuthority:  issuingAuthority,
-			IsAdult:           isAdult,
-			DocumentNullifier: nullifier.String(),
-			CredentialHash:    credentialHash.String(),
-		},
-		Expiration:     expiration,
-		MtProof:        true,
-		SignatureProof: true,
-	}
-
-	response, err := is.client.R().
-		SetBodyJsonMarshal(credentialRequest).
-		SetSuccessResult(&result).
-		Post("/credentials")
+		CredentialSchema:  template.Schema,
+		Type:              template.Type,
+		CredentialSubject: subject,
+		Expiration:        expiration,
+		MtProof:           true,
+		SignatureProof:    true,
+	}
+
+	response, err := is.do(func() (*req.Response, error) {
+		return is.client.R().
+			SetBodyJsonMarshal(credentialRequest).
+			SetSuccessResult(&result).
+			Post("/credentials")
+	})
 	if err != nil {
 		return "", errors.Wrap(err, "failed to send post request")
 	}
 
 	if response.StatusCode >= 299 {
-		return "", errors.Wrap(ErrUnexpectedStatusCode, response.String())
+		return "", responseError(response)
 	}
 
 	return result.Id, nil
 }
 
+// IsEmbedded reports whether the credentials are issued by the embedded
+// identity rather than by the Issuer Node
+func (is *Issuer) IsEmbedded() bool {
+	return is.identity != nil
+}
+
+// CredentialState returns the first state of the embedded identity including
+// the credential claim, nil until the claim is collected into a state
+func (is *Issuer) CredentialState(claimID uuid.UUID) (*string, error) {
+	if is.identity == nil {
+		return nil, errors.New("credential states are tracked only by the embedded issuer")
+	}
+
+	credential, _, err := is.identity.Credential(claimID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get embedded credential")
+	}
+
+	if credential == nil {
+		return nil, errors.From(ErrNotFound, logan.F{"credential_id": claimID.String()})
+	}
+
+	return credential.State, nil
+}
+
+// StateTransition returns the pending transition of the embedded identity
+// with the circuit inputs known only to the issuer, nil if there is none
+func (is *Issuer) StateTransition() (*embedded.StateTransition, error) {
+	if is.identity == nil {
+		return nil, errors.New("state transitions are recorded only by the embedded issuer")
+	}
+
+	transition, err := is.identity.StateTransition()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get embedded state transition")
+	}
+
+	return transition, nil
+}
+
+// ClaimProof returns the signed claim of the embedded credential with its
+// proofs against the latest published state of the identity
+func (is *Issuer) ClaimProof(claimID uuid.UUID) (*embedded.ClaimProof, error) {
+	if is.identity == nil {
+		return nil, errors.New("claim proofs are served only by the embedded issuer")
+	}
+
+	proof, err := is.identity.ClaimProof(claimID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get embedded claim proof")
+	}
+
+	if proof == nil {
+		return nil, errors.From(ErrNotFound, logan.F{"credential_id": claimID.String()})
+	}
+
+	return proof, nil
+}
+
 func (is *Issuer) GetCredential(claimID uuid.UUID) (GetCredentialResponse, error) {
+	if is.identity != nil {
+		return is.getEmbedded(claimID)
+	}
+
 	var cred GetCredentialResponse
 
-	response, err := is.client.R().
-		SetSuccessResult(&cred).
-		SetPathParam("id", claimID.String()).
-		Get("/credentials/{id}")
+	response, err := is.do(func() (*req.Response, error) {
+		return is.idempotent(is.client.R()).
+			SetSuccessResult(&cred).
+			SetPathParam("id", claimID.String()).
+			Get("/credentials/{id}")
+	})
 	if err != nil {
 		return GetCredentialResponse{}, errors.Wrap(err, "failed to send post request")
 	}
 
 	if response.StatusCode >= 299 {
-		return GetCredentialResponse{}, errors.Wrap(ErrUnexpectedStatusCode, response.String())
+		return GetCredentialResponse{}, responseError(response)
 	}
 
 	return cred, nil
 }
 
+// CredentialMTPState returns the issuer state of the Merkle tree proof of the
+// Issuer Node credential, nil until the credential has the proof. The state
+// is read from the W3C credential, so it is the state the claim is proven in.
+func (is *Issuer) CredentialMTPState(claimID uuid.UUID) (*string, error) {
+	if is.identity != nil {
+		return nil, errors.New("credential proofs of the embedded issuer are not in the Issuer Node")
+	}
+
+	var cred W3CCredentialResponse
+
+	response, err := is.do(func() (*req.Response, error) {
+		return is.idempotent(is.coreClient.R()).
+			SetSuccessResult(&cred).
+			SetPathParam("identifier", is.did).
+			SetPathParam("id", claimID.String()).
+			Get("/{identifier}/claims/{id}")
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send get request")
+	}
+
+	if response.StatusCode >= 299 {
+		return nil, responseError(response)
+	}
+
+	return cred.MTPState(), nil
+}
+
+// FindCredential looks up the credential of the template issued to the
+// subject with the given document nullifier, nil is returned if there is none
+func (is *Issuer) FindCredential(
+	template config.CredentialTemplate, subjectDID, nullifier string,
+) (*GetCredentialResponse, error) {
+	if is.identity != nil {
+		return is.findEmbedded(template, subjectDID, nullifier)
+	}
+
+	var creds []GetCredentialResponse
+
+	response, err := is.do(func() (*req.Response, error) {
+		return is.idempotent(is.client.R()).
+			SetSuccessResult(&creds).
+			SetQueryParams(map[string]string{
+				"did":    subjectDID,
+				"status": "all",
+			}).
+			Get("/credentials")
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send get request")
+	}
+
+	if response.StatusCode >= 299 {
+		return nil, responseError(response)
+	}
+
+	nullifierField := template.NullifierField()
+	for _, cred := range creds {
+		var subject map[string]any
+		if err := json.Unmarshal(cred.CredentialSubject, &subject); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal credential subject", logan.F{
+				"credential_id": cred.Id,
+			})
+		}
+
+		if subject[nullifierField] == nullifier {
+			return &cred, nil
+		}
+	}
+
+	return nil, nil
+}
+
 func (is *Issuer) RevokeClaim(revocationNonce int64) error {
-	response, err := is.client.R().
-		SetPathParam("nonce", strconv.FormatInt(revocationNonce, 10)).
-		Post("/credentials/revoke/{nonce}")
+	if is.identity != nil {
+		if err := is.identity.Revoke(revocationNonce); err != nil {
+			return errors.Wrap(err, "failed to revoke embedded claim")
+		}
+		return nil
+	}
+
+	response, err := is.do(func() (*req.Response, error) {
+		return is.client.R().
+			SetPathParam("nonce", strconv.FormatInt(revocationNonce, 10)).
+			Post("/credentials/revoke/{nonce}")
+	})
 	if err != nil {
 		return errors.Wrap(err, "failed to send post request")
 	}
 
 	if response.StatusCode >= 299 {
-		return errors.Wrap(ErrUnexpectedStatusCode, response.String())
+		return responseError(response)
 	}
 
 	return nil
@@ -0,0 +1,93 @@
+package issuer
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/iden3/go-iden3-core/v2/w3c"
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"gitlab.com/distributed_lab/logan/v3"
+)
+
+const issuerDID = "did:iden3:polygon:mumbai:x6suHR8HkEYczV9yVeAKKiXCZAd25P8WS6QvNhszk"
+
+// w3cCredential is the W3C credential of the Issuer Node core API with the
+// signature proof and, if state is set, the Merkle tree proof
+func w3cCredential(state string) string {
+	proofs := `{"type": "BJJSignature2021", "issuerData": {"id": "` + issuerDID + `", "state": {"value": "1"}}}`
+	if state != "" {
+		proofs += `, {"type": "Iden3SparseMerkleTreeProof", "issuerData": {"id": "` + issuerDID +
+			`", "state": {"value": "` + state + `", "claimsTreeRoot": "2"}}, "mtp": {"existence": true, "siblings": []}}`
+	}
+
+	return `{"id": "urn:uuid:1", "type": ["VerifiableCredential"], "proof": [` + proofs + `]}`
+}
+
+func TestCredentialMTPState(t *testing.T) {
+	claimID := uuid.New()
+
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		expected   *string
+		wantErr    bool
+	}{
+		{"published credential", http.StatusOK, w3cCredential("42"), strPtr("42"), false},
+		{"credential without Merkle tree proof", http.StatusOK, w3cCredential(""), nil, false},
+		{"missing credential", http.StatusNotFound, `{"message": "claim not found"}`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if expected := fmt.Sprintf("/v1/%s/claims/%s", issuerDID, claimID); r.URL.Path != expected {
+					t.Errorf("expected path %s, got %s", expected, r.URL.Path)
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.statusCode)
+				io.WriteString(w, tt.body)
+			}))
+			defer server.Close()
+
+			did, err := w3c.ParseDID(issuerDID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			iss := New(logan.New().Out(io.Discard), &config.IssuerConfig{
+				BaseUrl:     server.URL + "/ui/v1",
+				CoreBaseUrl: server.URL + "/v1",
+				DID:         did,
+				ClaimType:   "VotingCredential",
+			}, &config.CredentialsConfig{}, "", "")
+
+			state, err := iss.CredentialMTPState(claimID)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			switch {
+			case tt.expected == nil && state != nil:
+				t.Fatalf("expected no state, got %s", *state)
+			case tt.expected != nil && (state == nil || *state != *tt.expected):
+				t.Fatalf("expected state %s, got %v", *tt.expected, state)
+			}
+		})
+	}
+}
+
+func strPtr(value string) *string {
+	return &value
+}
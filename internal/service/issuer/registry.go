@@ -0,0 +1,84 @@
+package issuer
+
+import (
+	"time"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+)
+
+// Registry holds the issuer profiles and routes the registrations to them
+type Registry struct {
+	defaultIssuer *Issuer
+	profiles      map[string]*Issuer
+	byDID         map[string]*Issuer
+	rules         []config.IssuerRule
+}
+
+// NewRegistry creates the registry of the default issuer and the additional
+// profiles by their names
+func NewRegistry(defaultIssuer *Issuer, profiles map[string]*Issuer, rules []config.IssuerRule) *Registry {
+	registry := &Registry{
+		defaultIssuer: defaultIssuer,
+		profiles:      map[string]*Issuer{config.DefaultIssuerProfile: defaultIssuer},
+		byDID:         map[string]*Issuer{defaultIssuer.DID(): defaultIssuer},
+		rules:         rules,
+	}
+
+	for name, iss := range profiles {
+		registry.profiles[name] = iss
+		registry.byDID[iss.DID()] = iss
+	}
+
+	return registry
+}
+
+// Default is the issuer from the issuer section of the config
+func (r *Registry) Default() *Issuer {
+	return r.defaultIssuer
+}
+
+// ByDID returns the issuer of the claims issued with the DID, false if no
+// profile has it
+func (r *Registry) ByDID(did string) (*Issuer, bool) {
+	iss, ok := r.byDID[did]
+	return iss, ok
+}
+
+// RetryAfter is the time left until the calls to the issuer with the DID
+// are tried again, the default issuer is used if no profile has the DID
+func (r *Registry) RetryAfter(did string) time.Duration {
+	if iss, ok := r.ByDID(did); ok {
+		return iss.RetryAfter()
+	}
+
+	return r.defaultIssuer.RetryAfter()
+}
+
+// Route returns the issuer of the first rule matching the requested
+// credential type and the document issuing authority, the default issuer if
+// none matches
+func (r *Registry) Route(credentialType string, issuingAuthority int64) *Issuer {
+	for _, rule := range r.rules {
+		if rule.Matches(credentialType, issuingAuthority) {
+			return r.profiles[rule.Profile]
+		}
+	}
+
+	return r.defaultIssuer
+}
+
+// Profiles returns the issuers by the profile names, including the default
+// one
+func (r *Registry) Profiles() map[string]*Issuer {
+	return r.profiles
+}
+
+// BreakerStates returns the circuit breaker states by the profile names
+func (r *Registry) BreakerStates() map[string]string {
+	states := make(map[string]string, len(r.profiles))
+	for name, iss := range r.profiles {
+		states[name] = iss.BreakerState()
+	}
+
+	return states
+}
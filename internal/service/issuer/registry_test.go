@@ -0,0 +1,88 @@
+package issuer
+
+import (
+	"io"
+	"testing"
+
+	"github.com/iden3/go-iden3-core/v2/w3c"
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"gitlab.com/distributed_lab/logan/v3"
+)
+
+func newTestIssuer(t *testing.T, did string) *Issuer {
+	t.Helper()
+
+	parsed, err := w3c.ParseDID(did)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return New(logan.New().Out(io.Discard), &config.IssuerConfig{
+		DID:              parsed,
+		ClaimType:        "VotingCredential",
+		CredentialSchema: "https://example.com/schema.json",
+	}, &config.CredentialsConfig{}, "", "")
+}
+
+func TestRegistryRoute(t *testing.T) {
+	defaultIssuer := newTestIssuer(t, "did:example:default")
+	profiles := map[string]*Issuer{
+		"first":  newTestIssuer(t, "did:example:first"),
+		"second": newTestIssuer(t, "did:example:second"),
+	}
+
+	// the broader rule of the second profile is listed first, so the
+	// narrower one of the first profile never matches its authority
+	registry := NewRegistry(defaultIssuer, profiles, []config.IssuerRule{
+		{Profile: "second", IssuingAuthorities: []int64{1, 2}},
+		{Profile: "first", CredentialTypes: []string{"PassportCredential"}, IssuingAuthorities: []int64{1}},
+		{Profile: "first", CredentialTypes: []string{"PassportCredential"}},
+		{Profile: config.DefaultIssuerProfile, IssuingAuthorities: []int64{3}},
+		{Profile: "second"},
+	})
+
+	tests := []struct {
+		name             string
+		credentialType   string
+		issuingAuthority int64
+		expected         string
+	}{
+		{"first rule wins over narrower one", "PassportCredential", 1, "did:example:second"},
+		{"first rule", "", 2, "did:example:second"},
+		{"later rule", "PassportCredential", 3, "did:example:first"},
+		{"rule of default profile", "", 3, "did:example:default"},
+		{"last rule", "", 4, "did:example:second"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if did := registry.Route(tt.credentialType, tt.issuingAuthority).DID(); did != tt.expected {
+				t.Errorf("expected issuer %s, got %s", tt.expected, did)
+			}
+		})
+	}
+
+	t.Run("default issuer without matching rule", func(t *testing.T) {
+		registry := NewRegistry(defaultIssuer, profiles, []config.IssuerRule{
+			{Profile: "first", IssuingAuthorities: []int64{1}},
+		})
+
+		if iss := registry.Route("", 2); iss != defaultIssuer {
+			t.Errorf("expected default issuer, got %s", iss.DID())
+		}
+	})
+}
+
+func TestRegistryByDID(t *testing.T) {
+	defaultIssuer := newTestIssuer(t, "did:example:default")
+	first := newTestIssuer(t, "did:example:first")
+	registry := NewRegistry(defaultIssuer, map[string]*Issuer{"first": first}, nil)
+
+	if iss, ok := registry.ByDID("did:example:first"); !ok || iss != first {
+		t.Error("expected issuer of the first profile")
+	}
+
+	if _, ok := registry.ByDID("did:example:unknown"); ok {
+		t.Error("expected no issuer for unknown DID")
+	}
+}
@@ -0,0 +1,103 @@
+package issuer
+
+import (
+	"math/big"
+
+	"github.com/iden3/go-iden3-crypto/poseidon"
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// CredentialData is the verified document data the credentials are built from
+type CredentialData struct {
+	IssuingAuthority  int64
+	IsAdult           bool
+	DocumentNullifier *big.Int
+}
+
+// credentialSubject builds the credential subject of the template, the
+// subject id is set to the user DID
+func credentialSubject(template config.CredentialTemplate, subjectDID string, data CredentialData) (map[string]any, error) {
+	subject := map[string]any{
+		"id": subjectDID,
+	}
+
+	for _, field := range template.Subject {
+		switch field.Source {
+		case config.CredentialFieldIssuingAuthority:
+			subject[field.Field] = data.IssuingAuthority
+		case config.CredentialFieldIsAdult:
+			subject[field.Field] = data.IsAdult
+		case config.CredentialFieldDocumentNullifier:
+			subject[field.Field] = data.DocumentNullifier.String()
+		case config.CredentialFieldCredentialHash:
+			credentialHash, err := credentialHash(template, data)
+			if err != nil {
+				return nil, err
+			}
+			subject[field.Field] = credentialHash.String()
+		default:
+			return nil, errors.From(errors.New("unknown subject field source"), logan.F{
+				"field":  field.Field,
+				"source": field.Source,
+			})
+		}
+	}
+
+	return subject, nil
+}
+
+// CredentialHash is the hash of the credential built from the template, nil
+// if the template has no hash inputs
+func CredentialHash(template config.CredentialTemplate, data CredentialData) (*big.Int, error) {
+	if len(template.Hash) == 0 {
+		return nil, nil
+	}
+
+	return credentialHash(template, data)
+}
+
+// credentialHash is the Poseidon hash of the template hash inputs
+func credentialHash(template config.CredentialTemplate, data CredentialData) (*big.Int, error) {
+	inputs := make([]*big.Int, 0, len(template.Hash))
+	for _, input := range template.Hash {
+		if value, ok := new(big.Int).SetString(input, 10); ok {
+			inputs = append(inputs, value)
+			continue
+		}
+
+		value, err := fieldValue(template, data, input)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid hash input")
+		}
+		inputs = append(inputs, value)
+	}
+
+	result, err := poseidon.Hash(inputs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to hash credential inputs")
+	}
+
+	return result, nil
+}
+
+// fieldValue is the document data field as the field element, is_adult is
+// either 1 or 0
+func fieldValue(template config.CredentialTemplate, data CredentialData, field string) (*big.Int, error) {
+	switch field {
+	case config.CredentialFieldIssuingAuthority:
+		return big.NewInt(data.IssuingAuthority), nil
+	case config.CredentialFieldIsAdult:
+		if data.IsAdult {
+			return big.NewInt(1), nil
+		}
+		return big.NewInt(0), nil
+	case config.CredentialFieldDocumentNullifier:
+		return data.DocumentNullifier, nil
+	case config.CredentialFieldCredentialHash:
+		return credentialHash(template, data)
+	default:
+		return nil, errors.From(errors.New("unknown document data field"), logan.F{"field": field})
+	}
+}
@@ -0,0 +1,93 @@
+package issuer
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/iden3/go-iden3-crypto/poseidon"
+	"github.com/rarimo/passport-identity-provider/internal/config"
+)
+
+// votingSubject is the voting credential subject issued before the templates
+// were introduced
+type votingSubject struct {
+	ID                string `json:"id"`
+	IsAdult           bool   `json:"isAdult"`
+	IssuingAuthority  int64  `json:"issuingAuthority"`
+	DocumentNullifier string `json:"documentNullifier"`
+	CredentialHash    string `json:"credentialHash"`
+}
+
+func TestVotingCredentialTemplate(t *testing.T) {
+	const subjectDID = "did:iden3:readonly:tJ93RwaVfE1PEMxd5rpZZuPtLCwbEaDCrNBhAy8HM"
+
+	template := config.VotingCredentialTemplate("VotingCredential", "https://example.com/schema.json", "")
+	data := CredentialData{
+		IssuingAuthority:  4903594,
+		IsAdult:           true,
+		DocumentNullifier: big.NewInt(123456789),
+	}
+
+	expectedHash, err := poseidon.Hash([]*big.Int{big.NewInt(1), big.NewInt(data.IssuingAuthority), data.DocumentNullifier})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credentialHash, err := CredentialHash(template, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if credentialHash.Cmp(expectedHash) != 0 {
+		t.Fatalf("expected credential hash %s, got %s", expectedHash, credentialHash)
+	}
+
+	subject, err := credentialSubject(template, subjectDID, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the subjects are compared as they are sent to the Issuer Node
+	expected := toJSONMap(t, votingSubject{
+		ID:                subjectDID,
+		IsAdult:           data.IsAdult,
+		IssuingAuthority:  data.IssuingAuthority,
+		DocumentNullifier: data.DocumentNullifier.String(),
+		CredentialHash:    expectedHash.String(),
+	})
+	if actual := toJSONMap(t, subject); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected subject %v, got %v", expected, actual)
+	}
+}
+
+func TestCredentialHashWithoutInputs(t *testing.T) {
+	template := config.VotingCredentialTemplate("VotingCredential", "https://example.com/schema.json", "")
+	template.Hash = nil
+
+	credentialHash, err := CredentialHash(template, CredentialData{DocumentNullifier: big.NewInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if credentialHash != nil {
+		t.Fatalf("expected no credential hash, got %s", credentialHash)
+	}
+}
+
+func toJSONMap(t *testing.T, value any) map[string]any {
+	t.Helper()
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(raw, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	return result
+}
@@ -2,33 +2,28 @@ package issuer
 
 import (
 	"encoding/json"
-	"gitlab.com/distributed_lab/logan/v3/errors"
 	"time"
 )
 
-var (
-	ErrUnexpectedStatusCode = errors.New("unexpected status code")
-)
+// ProofTypeMTP is listed in the credential proof types once the claim is
+// published on-chain
+const ProofTypeMTP = "Iden3SparseMerkleTreeProof"
+
+// ProofTypeSignature is the proof type of the credentials signed by the
+// issuer key
+const ProofTypeSignature = "BJJSignature2021"
 
 type UUIDResponse struct {
 	Id string `json:"id"`
 }
 
 type CredentialRequest struct {
-	CredentialSchema  string            `json:"credentialSchema"`
-	Type              string            `json:"type"`
-	CredentialSubject CredentialSubject `json:"credentialSubject"`
-	Expiration        *time.Time        `json:"expiration,omitempty"`
-	MtProof           bool              `json:"mtProof"`
-	SignatureProof    bool              `json:"signatureProof"`
-}
-
-type CredentialSubject struct {
-	ID                string `json:"id"`
-	IsAdult           bool   `json:"isAdult"`
-	IssuingAuthority  int64  `json:"issuingAuthority"`
-	DocumentNullifier string `json:"documentNullifier"`
-	CredentialHash    string `json:"credentialHash"`
+	CredentialSchema  string         `json:"credentialSchema"`
+	Type              string         `json:"type"`
+	CredentialSubject map[string]any `json:"credentialSubject"`
+	Expiration        *time.Time     `json:"expiration,omitempty"`
+	MtProof           bool           `json:"mtProof"`
+	SignatureProof    bool           `json:"signatureProof"`
 }
 
 type GetCredentialResponse struct {
@@ -46,3 +41,46 @@ type GetCredentialResponse struct {
 	UserID                string          `json:"userID"`
 	SchemaTypeDescription string          `json:"schemaTypeDescription"`
 }
+
+// HasMTP reports whether the Merkle tree proof of the credential is available
+func (c GetCredentialResponse) HasMTP() bool {
+	for _, proofType := range c.ProofTypes {
+		if proofType == ProofTypeMTP {
+			return true
+		}
+	}
+
+	return false
+}
+
+// W3CCredentialResponse is the W3C credential of the Issuer Node core API,
+// only its proofs are read
+type W3CCredentialResponse struct {
+	Proof []CredentialProof `json:"proof"`
+}
+
+type CredentialProof struct {
+	Type       string          `json:"type"`
+	IssuerData ProofIssuerData `json:"issuerData"`
+}
+
+// ProofIssuerData is the issuer of the proof, the state of the Merkle tree
+// proof is the issuer state the claim is proven in
+type ProofIssuerData struct {
+	ID    string `json:"id"`
+	State struct {
+		Value *string `json:"value"`
+	} `json:"state"`
+}
+
+// MTPState returns the issuer state of the Merkle tree proof of the
+// credential, nil until the credential has the proof
+func (c W3CCredentialResponse) MTPState() *string {
+	for _, proof := range c.Proof {
+		if proof.Type == ProofTypeMTP {
+			return proof.IssuerData.State.Value
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package outbox
+
+import (
+	"math/big"
+
+	"github.com/google/uuid"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+func parseClaimID(id string) (uuid.UUID, error) {
+	claimID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, errors.Wrap(err, "failed to parse claim ID", logan.F{"claim_id": id})
+	}
+
+	return claimID, nil
+}
+
+func stringToBigInt(s string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, errors.From(errors.New("invalid decimal number"), logan.F{"value": s})
+	}
+
+	return n, nil
+}
+
+// isBanned reports whether the document of the issuance is banned, the
+// document is locked if lock is set. Issuances without the document hash are
+// never banned.
+func isBanned(db data.MasterQ, issuance data.Issuance, lock bool) (bool, error) {
+	if issuance.DocumentHash == nil {
+		return false, nil
+	}
+
+	q := db.Document().FilterBy("document_hash", *issuance.DocumentHash)
+	if lock {
+		q = q.ForUpdate()
+	}
+
+	document, err := q.Get()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get issuance document", logan.F{
+			"document_hash": *issuance.DocumentHash,
+		})
+	}
+
+	return document != nil && document.IsBanned, nil
+}
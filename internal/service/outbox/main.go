@@ -0,0 +1,412 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+	"gitlab.com/distributed_lab/running"
+)
+
+const (
+	// failureCodeDocumentBanned matches the registration rejection code of
+	// the banned documents
+	failureCodeDocumentBanned = "document_banned"
+	failureCodeInternal       = "internal_error"
+)
+
+var (
+	// ErrDocumentBanned is returned if the document of the issuance is banned
+	// before its credential is issued, the issuance fails
+	ErrDocumentBanned = errors.New("document is banned")
+
+	errIssuerNotConfigured         = errors.New("issuer of the issuance is not configured")
+	errCredentialTypeNotConfigured = errors.New("credential type is not configured")
+	errInvalidNullifier            = errors.New("issuance nullifier is invalid")
+)
+
+// Outbox creates the credentials of the recorded issuances in the issuer. The
+// issuer is never called inside a database transaction, an issuance is leased
+// for the time of the call instead, and the claim is inserted afterwards.
+// Retries look up the credential by its nullifier first, so a credential
+// created by a failed attempt is not issued twice.
+type Outbox struct {
+	log     *logan.Entry
+	q       data.MasterQ
+	issuers *issuer.Registry
+	cfg     *config.OutboxConfig
+}
+
+func New(log *logan.Entry, q data.MasterQ, issuers *issuer.Registry, cfg *config.OutboxConfig) *Outbox {
+	return &Outbox{
+		log:     log,
+		q:       q,
+		issuers: issuers,
+		cfg:     cfg,
+	}
+}
+
+// Lease is the time the issuance recorded by a request is reserved for the
+// request to process it
+func (o *Outbox) Lease() time.Duration {
+	return o.cfg.Lease
+}
+
+// Run retries the pending issuances until ctx is done
+func (o *Outbox) Run(ctx context.Context) {
+	running.WithBackOff(ctx, o.log, "issuance-outbox", o.processPending,
+		o.cfg.Period, o.cfg.MinRetryDelay, o.cfg.MaxRetryDelay)
+}
+
+func (o *Outbox) processPending(_ context.Context) error {
+	var issuances []data.Issuance
+	err := o.q.Transaction(func(db data.MasterQ) error {
+		var err error
+		issuances, err = db.Issuance().
+			FilterDue(time.Now().UTC()).
+			Limit(o.cfg.BatchSize).
+			ForUpdateSkipLocked().
+			Select()
+		if err != nil {
+			return errors.Wrap(err, "failed to select pending issuances")
+		}
+
+		for i := range issuances {
+			if err := o.lease(db, &issuances[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to lease pending issuances")
+	}
+
+	for _, issuance := range issuances {
+		if _, err := o.attempt(o.q, issuance, true); err != nil {
+			log := o.log.WithError(err).WithFields(logan.F{
+				"issuance_id": issuance.ID,
+				"attempts":    issuance.Attempts,
+			})
+			if IsPermanent(err) || issuance.Attempts >= o.cfg.MaxAttempts {
+				log.Error("issuance failed")
+				continue
+			}
+			if issuer.IsMisconfiguration(err) {
+				log.Error("issuance attempt failed, issuer node rejected the configuration")
+				continue
+			}
+			log.Warn("issuance attempt failed")
+		}
+	}
+
+	return nil
+}
+
+// lease reserves the issuance for a single attempt, the next attempt is
+// scheduled in case this one is interrupted
+func (o *Outbox) lease(db data.MasterQ, issuance *data.Issuance) error {
+	issuance.Attempts++
+	issuance.NextAttemptAt = time.Now().UTC().Add(o.cfg.Lease + o.retryDelay(issuance.Attempts))
+
+	err := db.Issuance().FilterBy("id", issuance.ID).Update(map[string]any{
+		"attempts":        issuance.Attempts,
+		"next_attempt_at": issuance.NextAttemptAt,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to lease issuance", logan.F{"issuance_id": issuance.ID})
+	}
+
+	return nil
+}
+
+// NewLeasedIssuance prepares the issuance to be recorded by a request that is
+// going to process it right away
+func (o *Outbox) NewLeasedIssuance(issuance data.Issuance) data.Issuance {
+	issuance.Status = data.IssuanceStatusPending
+	issuance.Attempts = 1
+	issuance.NextAttemptAt = time.Now().UTC().Add(o.cfg.Lease + o.retryDelay(issuance.Attempts))
+	return issuance
+}
+
+// Process creates the credential of the issuance recorded with
+// NewLeasedIssuance and inserts its claim. On failure the error is recorded
+// and the issuance is retried by the worker once the lease is over, unless
+// the failure is permanent or the attempts are exhausted, then the issuance
+// and its registration fail.
+func (o *Outbox) Process(db data.MasterQ, issuance data.Issuance) (*data.Claim, error) {
+	return o.attempt(db, issuance, false)
+}
+
+// attempt processes the issuance, the re-leased one could have its
+// credential created by an attempt whose lease is over
+func (o *Outbox) attempt(db data.MasterQ, issuance data.Issuance, released bool) (*data.Claim, error) {
+	claim, err := o.process(db, issuance, released)
+	if err == nil {
+		return claim, nil
+	}
+
+	log := o.log.WithField("issuance_id", issuance.ID)
+	if !IsPermanent(err) && issuance.Attempts < o.cfg.MaxAttempts {
+		updErr := db.Issuance().FilterBy("id", issuance.ID).Update(map[string]any{
+			"last_error": err.Error(),
+		})
+		if updErr != nil {
+			log.WithError(updErr).Error("failed to record issuance error")
+		}
+
+		return nil, err
+	}
+
+	if failErr := o.fail(db, issuance, err); failErr != nil {
+		log.WithError(failErr).Error("failed to mark issuance as failed")
+	}
+
+	return nil, err
+}
+
+// IsPermanent reports whether the issuance can not succeed on retry, such
+// issuances fail right away
+func IsPermanent(err error) bool {
+	switch errors.Cause(err) {
+	case ErrDocumentBanned, errIssuerNotConfigured, errCredentialTypeNotConfigured, errInvalidNullifier,
+		issuer.ErrSchemaInvalid, issuer.ErrDIDNotFound:
+		return true
+	default:
+		return false
+	}
+}
+
+// fail moves the issuance to the final failed status, so it is not leased
+// anymore, and fails the registration waiting for it
+func (o *Outbox) fail(db data.MasterQ, issuance data.Issuance, cause error) error {
+	code, reason := failure(errors.Cause(cause).Error())
+
+	return db.Transaction(func(db data.MasterQ) error {
+		return o.failIssuance(db, issuance.ID, cause, code, reason)
+	})
+}
+
+// Failure returns the registration failure code and reason of the failed
+// issuance
+func Failure(issuance data.Issuance) (code, reason string) {
+	if issuance.LastError == nil {
+		return failure("")
+	}
+	return failure(*issuance.LastError)
+}
+
+func failure(lastError string) (code, reason string) {
+	if lastError == ErrDocumentBanned.Error() {
+		return failureCodeDocumentBanned, "Document is banned"
+	}
+	return failureCodeInternal, "Credential could not be issued"
+}
+
+func (o *Outbox) failIssuance(db data.MasterQ, issuanceID int64, cause error, code, reason string) error {
+	if err := db.Issuance().FilterBy("id", issuanceID).Update(map[string]any{
+		"status":     data.IssuanceStatusFailed,
+		"last_error": cause.Error(),
+	}); err != nil {
+		return errors.Wrap(err, "failed to mark issuance as failed")
+	}
+
+	if err := db.Registration().FilterBy("issuance_id", issuanceID).Update(map[string]any{
+		"status":         data.RegistrationStatusFailed,
+		"failure_code":   code,
+		"failure_reason": reason,
+		"updated_at":     time.Now().UTC(),
+	}); err != nil {
+		return errors.Wrap(err, "failed to mark registration as failed")
+	}
+
+	return nil
+}
+
+func (o *Outbox) process(db data.MasterQ, issuance data.Issuance, released bool) (*data.Claim, error) {
+	iss, err := o.issuerOf(issuance)
+	if err != nil {
+		return nil, err
+	}
+
+	template, ok := iss.Template(issuance.CredentialType)
+	if !ok {
+		return nil, errors.From(errCredentialTypeNotConfigured, logan.F{
+			"credential_type": issuance.CredentialType,
+		})
+	}
+
+	nullifier, err := stringToBigInt(issuance.Nullifier)
+	if err != nil {
+		return nil, errors.From(errInvalidNullifier, logan.F{"nullifier": issuance.Nullifier})
+	}
+
+	credentialData := issuer.CredentialData{
+		IssuingAuthority:  issuance.IssuingAuthority,
+		IsAdult:           issuance.IsAdult,
+		DocumentNullifier: nullifier,
+	}
+
+	credentialHash, err := issuer.CredentialHash(template, credentialData)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build credential hash")
+	}
+
+	// the document could be banned after the issuance was recorded, the ban
+	// is checked again under the document lock once the credential exists
+	banned, err := isBanned(db, issuance, false)
+	if err != nil {
+		return nil, err
+	}
+	if banned {
+		return nil, ErrDocumentBanned
+	}
+
+	claimID, err := o.createCredential(iss, template, issuance, credentialData, released)
+	if err != nil {
+		return nil, err
+	}
+
+	claim := data.Claim{
+		ID:               claimID,
+		UserDID:          issuance.UserDID,
+		IssuerDID:        iss.DID(),
+		CredentialType:   issuance.CredentialType,
+		Nullifier:        issuance.Nullifier,
+		Salt:             issuance.Salt,
+		DocumentHash:     issuance.DocumentHash,
+		IssuingAuthority: &issuance.IssuingAuthority,
+		IsAdult:          &issuance.IsAdult,
+		Expiration:       issuance.Expiration,
+	}
+	if credentialHash != nil {
+		credentialHashStr := credentialHash.String()
+		claim.CredentialHash = &credentialHashStr
+	}
+
+	err = db.Transaction(func(db data.MasterQ) error {
+		// the ban is serialized with the claim insertion by the document
+		// lock, a ban committed before it does not see the claim, so its
+		// revocation is scheduled here
+		banned, err = isBanned(db, issuance, true)
+		if err != nil {
+			return err
+		}
+
+		if err := db.Claim().Insert(claim); err != nil {
+			return errors.Wrap(err, "failed to insert claim")
+		}
+
+		if banned {
+			if err := db.ClaimRevocation().ScheduleForClaims([]uuid.UUID{claimID}); err != nil {
+				return errors.Wrap(err, "failed to schedule claim revocation")
+			}
+
+			if err := db.Issuance().FilterBy("id", issuance.ID).Update(map[string]any{
+				"claim_id": claimID,
+			}); err != nil {
+				return errors.Wrap(err, "failed to link issuance claim")
+			}
+
+			code, reason := failure(ErrDocumentBanned.Error())
+			return o.failIssuance(db, issuance.ID, ErrDocumentBanned, code, reason)
+		}
+
+		if err := db.Issuance().FilterBy("id", issuance.ID).Update(map[string]any{
+			"status":     data.IssuanceStatusIssued,
+			"claim_id":   claimID,
+			"last_error": nil,
+		}); err != nil {
+			return errors.Wrap(err, "failed to mark issuance as issued")
+		}
+
+		if err := db.Registration().FilterBy("issuance_id", issuance.ID).Update(map[string]any{
+			"status":     data.RegistrationStatusIssued,
+			"claim_id":   claimID,
+			"updated_at": time.Now().UTC(),
+		}); err != nil {
+			return errors.Wrap(err, "failed to mark registration as issued")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to record issued claim", logan.F{"claim_id": claimID.String()})
+	}
+
+	log := o.log.WithFields(logan.F{
+		"issuance_id": issuance.ID,
+		"claim_id":    claimID.String(),
+	})
+	if banned {
+		log.Info("document was banned while credential was issued, credential revocation is scheduled")
+		return nil, ErrDocumentBanned
+	}
+
+	log.Debug("credential issued")
+
+	return &claim, nil
+}
+
+// issuerOf returns the issuer the issuance was routed to, the issuances
+// without the issuer DID belong to the default issuer
+func (o *Outbox) issuerOf(issuance data.Issuance) (*issuer.Issuer, error) {
+	if issuance.IssuerDID == nil {
+		return o.issuers.Default(), nil
+	}
+
+	iss, ok := o.issuers.ByDID(*issuance.IssuerDID)
+	if !ok {
+		return nil, errors.From(errIssuerNotConfigured, logan.F{
+			"issuer_did": *issuance.IssuerDID,
+		})
+	}
+
+	return iss, nil
+}
+
+// createCredential returns the credential created by a previous attempt of
+// the re-leased issuance if there is one, otherwise the credential is created
+func (o *Outbox) createCredential(
+	iss *issuer.Issuer, template config.CredentialTemplate, issuance data.Issuance, credentialData issuer.CredentialData,
+	released bool,
+) (uuid.UUID, error) {
+	if released {
+		cred, err := iss.FindCredential(template, issuance.UserDID, issuance.Nullifier)
+		if err != nil {
+			return uuid.Nil, errors.Wrap(err, "failed to look up credential created by previous attempts")
+		}
+
+		if cred != nil {
+			return parseClaimID(cred.Id)
+		}
+	}
+
+	claimID, err := iss.IssueCredential(template, issuance.UserDID, credentialData, issuance.Expiration)
+	if err != nil {
+		return uuid.Nil, errors.Wrap(err, "failed to issue credential", logan.F{
+			"credential_type": template.Type,
+		})
+	}
+
+	return parseClaimID(claimID)
+}
+
+func (o *Outbox) retryDelay(attempts int) time.Duration {
+	delay := o.cfg.MinRetryDelay
+	for i := 1; i < attempts && delay < o.cfg.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > o.cfg.MaxRetryDelay {
+		delay = o.cfg.MaxRetryDelay
+	}
+
+	return delay
+}
@@ -0,0 +1,200 @@
+package registration
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/rarimo/certificate-transparency-go/x509"
+	"github.com/rarimo/passport-identity-provider/internal/service/api/requests"
+	"github.com/rarimo/passport-identity-provider/resources"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// Full list of the OpenSSL signature algorithms and hash-functions is provided here:
+// https://www.openssl.org/docs/man1.1.1/man3/SSL_CTX_set1_sigalgs_list.html
+
+const (
+	SHA1   = "sha1"
+	SHA256 = "sha256"
+
+	SHA1withRSA     = "SHA1withRSA"
+	SHA256withRSA   = "SHA256withRSA"
+	SHA1withECDSA   = "SHA1withECDSA"
+	SHA256withECDSA = "SHA256withECDSA"
+)
+
+var algorithmsListMap = map[string]map[string]string{
+	"SHA1": {
+		"ECDSA": SHA1withECDSA,
+		"RSA":   SHA1withRSA,
+	},
+	"SHA256": {
+		"RSA":   SHA256withRSA,
+		"ECDSA": SHA256withECDSA,
+	},
+}
+
+func parseCertificate(pemFile []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(pemFile)
+	if block == nil {
+		return nil, fmt.Errorf("invalid certificate: invalid PEM")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse certificate")
+	}
+
+	return cert, nil
+}
+
+func validateSignedAttributes(signedAttributes, encapsulatedContent []byte, algorithm string) error {
+	signedAttributesASN1 := make([]asn1.RawValue, 0)
+
+	if _, err := asn1.UnmarshalWithParams(signedAttributes, &signedAttributesASN1, "set"); err != nil {
+		return errors.Wrap(err, "failed to unmarshal ASN1 with params")
+	}
+
+	if len(signedAttributesASN1) == 0 {
+		return errors.New("signed attributes amount is 0")
+	}
+
+	digestAttr := resources.DigestAttribute{}
+	if _, err := asn1.Unmarshal(signedAttributesASN1[len(signedAttributesASN1)-1].FullBytes, &digestAttr); err != nil {
+		return errors.Wrap(err, "failed to unmarshal ASN1")
+	}
+
+	d := make([]byte, 0)
+	switch algorithm {
+	case SHA1withRSA, SHA1withECDSA:
+		h := sha1.New()
+		h.Write(encapsulatedContent)
+		d = h.Sum(nil)
+	case SHA256withRSA, SHA256withECDSA:
+		h := sha256.New()
+		h.Write(encapsulatedContent)
+		d = h.Sum(nil)
+	default:
+		return errors.New(fmt.Sprintf("%s is not supported algorithm", algorithm))
+	}
+
+	if len(digestAttr.Digest) == 0 {
+		return errors.New("signed attributes digest values amount is 0")
+	}
+
+	if !bytes.Equal(digestAttr.Digest[0].Bytes, d) {
+		return errors.From(errors.New("digest signed attribute is not equal to encapsulated content hash"), logan.F{
+			"signed_attributes":    hex.EncodeToString(digestAttr.Digest[0].Bytes),
+			"content_hash":         hex.EncodeToString(d),
+			"encapsulated_content": hex.EncodeToString(encapsulatedContent),
+		})
+	}
+	return nil
+}
+
+func signatureAlgorithm(passedAlgorithm string) string {
+	if passedAlgorithm == "rsaEncryption" {
+		return SHA256withRSA
+	}
+
+	if passedAlgorithm == "RSA" {
+		return SHA256withRSA
+	}
+
+	if strings.Contains(strings.ToUpper(passedAlgorithm), "PSS") {
+		return "" // RSA-PSS is not currently supported
+	}
+
+	for hashFunc, signatureAlgorithms := range algorithmsListMap {
+		if strings.Contains(strings.ToUpper(passedAlgorithm), hashFunc) {
+			for signatureAlgo, algorithmName := range signatureAlgorithms {
+				if strings.Contains(strings.ToUpper(passedAlgorithm), signatureAlgo) {
+					return algorithmName
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func verifySignature(documentSOD requests.DocumentSOD, cert *x509.Certificate, signedAttributes []byte, algo string) error {
+	signature, err := hex.DecodeString(documentSOD.Signature)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode hex string")
+	}
+
+	switch algo {
+	case SHA1withRSA:
+		pubKey := cert.PublicKey.(*rsa.PublicKey)
+
+		h := sha1.New()
+		h.Write(signedAttributes)
+		d := h.Sum(nil)
+
+		if err := rsa.VerifyPKCS1v15(pubKey, crypto.SHA1, d, signature); err != nil {
+			return errors.Wrap(err, "failed to verify SHA1 with RSA signature")
+		}
+	case SHA256withRSA:
+		pubKey := cert.PublicKey.(*rsa.PublicKey)
+
+		h := sha256.New()
+		h.Write(signedAttributes)
+		d := h.Sum(nil)
+
+		if err := rsa.VerifyPKCS1v15(pubKey, crypto.SHA256, d, signature); err != nil {
+			return errors.Wrap(err, "failed to verify SHA256 with RSA signature")
+		}
+	case SHA1withECDSA:
+		pubKey := cert.PublicKey.(*ecdsa.PublicKey)
+
+		h := sha1.New()
+		h.Write(signedAttributes)
+		d := h.Sum(nil)
+
+		if !ecdsa.VerifyASN1(pubKey, d, signature) {
+			return errors.New("failed to verify SHA1 with ECDSA signature")
+		}
+	case SHA256withECDSA:
+		pubKey := cert.PublicKey.(*ecdsa.PublicKey)
+
+		h := sha256.New()
+		h.Write(signedAttributes)
+		d := h.Sum(nil)
+
+		if !ecdsa.VerifyASN1(pubKey, d, signature) {
+			return errors.New("failed to verify SHA256 with ECDSA signature")
+		}
+	default:
+		return errors.New(fmt.Sprintf("%s is unsupported algorithm", documentSOD.Algorithm))
+	}
+
+	return nil
+}
+
+func validateCert(cert *x509.Certificate, masterCertsPem []byte) error {
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(masterCertsPem)
+
+	foundCerts, err := cert.Verify(x509.VerifyOptions{
+		Roots: roots,
+	})
+	if err != nil {
+		return fmt.Errorf("invalid certificate: %w", err)
+	}
+
+	if len(foundCerts) == 0 {
+		return fmt.Errorf("invalid certificate: no valid certificate found")
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package registration
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/jsonapi"
+	"github.com/rarimo/passport-identity-provider/internal/service/verifier"
+	"gitlab.com/distributed_lab/ape/problems"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+const (
+	CodeDocumentBanned         = "document_banned"
+	CodeTooManyIdentities      = "too_many_identities"
+	CodeReregistrationCooldown = "reregistration_cooldown"
+)
+
+// Error rejects the registration, its problems are rendered to the client as
+// is. Failures that are not caused by the request are returned as plain
+// errors.
+type Error struct {
+	Problems []*jsonapi.ErrorObject
+	// RetryAfter is set for the rejections that can be retried later
+	RetryAfter time.Duration
+	// transient is set if the same request can succeed once the service
+	// recovers
+	transient bool
+	cause     error
+}
+
+func (e *Error) Error() string {
+	if e.cause == nil {
+		return e.Reason()
+	}
+	return e.cause.Error()
+}
+
+// Reason is the human-readable description of the rejection
+func (e *Error) Reason() string {
+	problem := e.Problems[0]
+	if problem.Detail != "" {
+		return problem.Detail
+	}
+	return problem.Title
+}
+
+// Transient reports whether the request was rejected because the service is
+// temporarily unavailable, rather than for the request itself
+func (e *Error) Transient() bool {
+	return e.transient
+}
+
+// Code is the application-specific error code, empty for the generic errors
+func (e *Error) Code() string {
+	return e.Problems[0].Code
+}
+
+func badRequest(err error) *Error {
+	return &Error{
+		Problems: problems.BadRequest(err),
+		cause:    err,
+	}
+}
+
+func unavailable(retryAfter time.Duration, err error) *Error {
+	return &Error{
+		Problems: []*jsonapi.ErrorObject{{
+			Title:  http.StatusText(http.StatusServiceUnavailable),
+			Status: fmt.Sprintf("%d", http.StatusServiceUnavailable),
+		}},
+		RetryAfter: retryAfter,
+		transient:  true,
+		cause:      err,
+	}
+}
+
+// verificationError rejects the request with the invalid proof, the requests
+// rejected by the full verification queue are retried after retryAfter
+func verificationError(err error, retryAfter time.Duration) *Error {
+	if errors.Cause(err) == verifier.ErrQueueFull {
+		return unavailable(retryAfter, err)
+	}
+
+	return badRequest(err)
+}
+
+// documentBanned rejects the documents banned before the request, the ban
+// reason is shown only if exposeDetails is set
+func documentBanned(reason *string, exposeDetails bool) *Error {
+	problem := &jsonapi.ErrorObject{
+		Title:  http.StatusText(http.StatusForbidden),
+		Status: fmt.Sprintf("%d", http.StatusForbidden),
+		Code:   CodeDocumentBanned,
+		Detail: "Document is banned",
+	}
+
+	if exposeDetails && reason != nil {
+		problem.Detail = fmt.Sprintf("Document is banned: %s", *reason)
+	}
+
+	return &Error{
+		Problems: []*jsonapi.ErrorObject{problem},
+		cause:    errors.New("user of the provided document is banned"),
+	}
+}
+
+// tooManyIdentities rejects the request exceeding the document multi-account
+// limit, the limit is shown only if exposeDetails is set
+func tooManyIdentities(registered, allowed int, exposeDetails bool) *Error {
+	problem := &jsonapi.ErrorObject{
+		Title:  http.StatusText(http.StatusTooManyRequests),
+		Status: fmt.Sprintf("%d", http.StatusTooManyRequests),
+		Code:   CodeTooManyIdentities,
+		Detail: "Too many identities were registered with the document, the document is banned",
+	}
+
+	if exposeDetails {
+		problem.Detail = fmt.Sprintf(
+			"Too many identities were registered with the document: %d registered, %d allowed, the document is banned",
+			registered, allowed,
+		)
+	}
+
+	return &Error{
+		Problems: []*jsonapi.ErrorObject{problem},
+		cause: errors.From(errors.New("multi-account limit exceeded"), logan.F{
+			"registered": registered,
+			"allowed":    allowed,
+		}),
+	}
+}
+
+// reregistrationCooldown rejects the re-registration requested before the
+// cool-down since the previous claim is over
+func reregistrationCooldown(retryAfter time.Duration) *Error {
+	return &Error{
+		Problems: []*jsonapi.ErrorObject{{
+			Title:  http.StatusText(http.StatusTooManyRequests),
+			Status: fmt.Sprintf("%d", http.StatusTooManyRequests),
+			Code:   CodeReregistrationCooldown,
+			Detail: "Document was registered recently, re-registration is not allowed yet",
+		}},
+		RetryAfter: retryAfter,
+		cause:      errors.New("re-registration cool-down is not over"),
+	}
+}
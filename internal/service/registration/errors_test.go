@@ -0,0 +1,60 @@
+package registration
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rarimo/passport-identity-provider/internal/service/verifier"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+func TestVerificationError(t *testing.T) {
+	const retryAfter = 5 * time.Second
+
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		retryAfter time.Duration
+		transient  bool
+	}{
+		{"queue full", verifier.ErrQueueFull, http.StatusServiceUnavailable, retryAfter, true},
+		{"wrapped queue full", errors.Wrap(verifier.ErrQueueFull, "failed to verify"), http.StatusServiceUnavailable, retryAfter, true},
+		{"invalid proof", verifier.ErrInvalidProof, http.StatusBadRequest, 0, false},
+		{"unknown key", verifier.ErrUnknownKey, http.StatusBadRequest, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rejection := verificationError(tt.err, retryAfter)
+
+			if status := rejection.Problems[0].Status; status != strconv.Itoa(tt.status) {
+				t.Errorf("expected status %d, got %s", tt.status, status)
+			}
+
+			if rejection.RetryAfter != tt.retryAfter {
+				t.Errorf("expected retry after %s, got %s", tt.retryAfter, rejection.RetryAfter)
+			}
+
+			if rejection.Transient() != tt.transient {
+				t.Errorf("expected transient %t, got %t", tt.transient, rejection.Transient())
+			}
+		})
+	}
+}
+
+func TestReregistrationCooldownIsNotTransient(t *testing.T) {
+	// the asynchronous registration fails with the cool-down code rather than
+	// waiting the cool-down out
+	rejection := reregistrationCooldown(time.Hour)
+
+	if rejection.Transient() {
+		t.Error("expected cool-down rejection not to be transient")
+	}
+
+	if rejection.Code() != CodeReregistrationCooldown {
+		t.Errorf("expected code %s, got %s", CodeReregistrationCooldown, rejection.Code())
+	}
+}
@@ -0,0 +1,383 @@
+package registration
+
+import (
+	"context"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/iden3/go-iden3-crypto/poseidon"
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/api/requests"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+	"github.com/rarimo/passport-identity-provider/internal/service/outbox"
+	"github.com/rarimo/passport-identity-provider/internal/service/vault"
+	"github.com/rarimo/passport-identity-provider/internal/service/verifier"
+	"github.com/rarimo/passport-identity-provider/resources"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// Registrar verifies the identity creation requests and records the
+// credential issuance for them
+type Registrar struct {
+	cfg      *config.VerifierConfig
+	verifier *verifier.Verifier
+	issuers  *issuer.Registry
+	vault    *vault.VaultClient
+	outbox   *outbox.Outbox
+}
+
+// Result of the registration, exactly one of the fields is set
+type Result struct {
+	// Claim is the claim already issued to the DID
+	Claim *data.Claim
+	// Issuance is the credential issuance recorded for the DID
+	Issuance *data.Issuance
+	// Leased is set if the issuance was recorded by this call and can be
+	// processed right away, otherwise it is processed by the outbox
+	Leased bool
+}
+
+func New(
+	cfg *config.VerifierConfig,
+	proofVerifier *verifier.Verifier,
+	issuers *issuer.Registry,
+	vaultClient *vault.VaultClient,
+	issuanceOutbox *outbox.Outbox,
+) *Registrar {
+	return &Registrar{
+		cfg:      cfg,
+		verifier: proofVerifier,
+		issuers:  issuers,
+		vault:    vaultClient,
+		outbox:   issuanceOutbox,
+	}
+}
+
+// Register verifies the request and records the credential issuance. The
+// request rejections are returned as *Error.
+func (r *Registrar) Register(
+	ctx context.Context, db data.MasterQ, log *logan.Entry, req requests.CreateIdentityRequestData,
+) (*Result, error) {
+	algorithm := signatureAlgorithm(req.DocumentSOD.Algorithm)
+	if algorithm == "" {
+		return nil, badRequest(fmt.Errorf("%s is not a valid algorithm", req.DocumentSOD.Algorithm))
+	}
+
+	signedAttributes, err := hex.DecodeString(req.DocumentSOD.SignedAttributes)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	encapsulatedContent, err := hex.DecodeString(req.DocumentSOD.EncapsulatedContent)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	if err := validateSignedAttributes(signedAttributes, encapsulatedContent, algorithm); err != nil {
+		return nil, errors.Wrap(err, "failed to validate signed attributes")
+	}
+
+	cert, err := parseCertificate([]byte(req.DocumentSOD.PemFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse certificate")
+	}
+
+	if err := verifySignature(req.DocumentSOD, cert, signedAttributes, algorithm); err != nil {
+		return nil, errors.Wrap(err, "failed to verify signature")
+	}
+
+	var verificationKey string
+	switch algorithm {
+	case SHA1withRSA, SHA1withECDSA:
+		verificationKey = SHA1
+	case SHA256withRSA, SHA256withECDSA:
+		verificationKey = SHA256
+	default:
+		return nil, badRequest(errors.New("invalid signature algorithm"))
+	}
+
+	if err := r.verifier.Verify(ctx, verificationKey, req.ZKProof); err != nil {
+		return nil, verificationError(err, r.verifier.RetryAfter())
+	}
+
+	encapsulatedData := resources.EncapsulatedData{}
+	if _, err = asn1.Unmarshal(encapsulatedContent, &encapsulatedData); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal ASN.1")
+	}
+
+	privateKey := make([]asn1.RawValue, 0)
+	if _, err = asn1.Unmarshal(encapsulatedData.PrivateKey.FullBytes, &privateKey); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal ASN.1")
+	}
+
+	privKeyEl := resources.PrivateKeyElement{}
+	if _, err = asn1.Unmarshal(privateKey[0].FullBytes, &privKeyEl); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal ASN.1")
+	}
+
+	issuingAuthority, err := strconv.Atoi(req.ZKProof.PubSignals[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to convert string to int")
+	}
+
+	// the requests without the credential type get the claim type of the
+	// issuer selected by the document
+	iss := r.issuers.Route(req.CredentialType, int64(issuingAuthority))
+	credentialType := req.CredentialType
+	if credentialType == "" {
+		credentialType = iss.ClaimType()
+	}
+
+	if _, ok := iss.Template(credentialType); !ok {
+		return nil, badRequest(validation.Errors{
+			"/data/credential_type": fmt.Errorf("credential type %s is not supported", credentialType),
+		})
+	}
+
+	agePolicy := r.cfg.AgePolicy(credentialType, int64(issuingAuthority))
+
+	if err := validatePubSignals(agePolicy, req, privKeyEl.OctetStr.Bytes); err != nil {
+		return nil, badRequest(err)
+	}
+
+	if err := validateCert(cert, r.cfg.MasterCerts); err != nil {
+		return nil, badRequest(err)
+	}
+
+	existing, err := existingResult(db, req.ID.String(), credentialType)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		return existing, nil
+	}
+
+	identityExpiration, err := getExpirationTimeFromPubSignals(req.ZKProof.PubSignals)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	age, err := getAgeFromPubSignals(req.ZKProof.PubSignals)
+	if err != nil {
+		return nil, badRequest(err)
+	}
+
+	blinder, err := r.vault.Blinder()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get blinder from the vault")
+	}
+
+	// timestamp is only 6 bytes long, if using some other salt, make sure that it is
+	// < 32 to be compatible with Poseidon hash function
+	salt := new(big.Int).SetUint64(uint64(time.Now().UTC().UnixMilli()))
+	documentHash, err := poseidon.HashBytes(signedAttributes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to hash signed attributes")
+	}
+
+	nullifier, err := poseidon.Hash([]*big.Int{documentHash, blinder, salt})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build nullifier")
+	}
+
+	// the secret is read before the document is locked, so that the other
+	// registrations of the document do not wait for Vault
+	multiAccSecret, err := r.vault.MultiAccSecret()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get multi account secret from the vault")
+	}
+
+	documentHashStr := documentHash.String()
+	issuerDID := iss.DID()
+	log = log.WithFields(logan.F{
+		"document_hash": documentHashStr,
+		"issuer_did":    issuerDID,
+	})
+
+	return r.record(db, log, documentRegistration{
+		issuance: data.Issuance{
+			UserDID:          req.ID.String(),
+			CredentialType:   credentialType,
+			IssuerDID:        &issuerDID,
+			DocumentHash:     &documentHashStr,
+			IssuingAuthority: int64(issuingAuthority),
+			IsAdult:          agePolicy.IsAdult(age),
+			Expiration:       identityExpiration,
+			Nullifier:        nullifier.String(),
+			Salt:             salt.String(),
+		},
+		documentHash:   documentHash,
+		reregistration: req.Reregistration,
+		multiAccSecret: multiAccSecret,
+	})
+}
+
+// existingResult returns the claim issued to the DID or the issuance of its
+// previous request, which is still pending, nil if there are none
+func existingResult(db data.MasterQ, userDID, credentialType string) (*Result, error) {
+	claim, err := db.Claim().ResetFilter().
+		FilterBy("user_did", userDID).
+		FilterBy("credential_type", credentialType).
+		FilterBy("revoked_at", nil).
+		FilterBy("expired_at", nil).
+		Get()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get claim by user DID")
+	}
+
+	if claim != nil {
+		return &Result{Claim: claim}, nil
+	}
+
+	pending, err := db.Issuance().
+		FilterBy("user_did", userDID).
+		FilterBy("credential_type", credentialType).
+		FilterBy("status", data.IssuanceStatusPending).
+		Get()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get pending issuance by user DID")
+	}
+
+	if pending != nil {
+		return &Result{Issuance: pending}, nil
+	}
+
+	return nil, nil
+}
+
+// documentRegistration is the verified request to be recorded for the
+// document
+type documentRegistration struct {
+	issuance       data.Issuance
+	documentHash   *big.Int
+	reregistration bool
+	// multiAccSecret derives the multi-account limit of the document
+	multiAccSecret []byte
+}
+
+// record checks the document ban and multi-account limit and records the
+// leased issuance. Registrations of the same document are serialized on its
+// row until the issuance is recorded, so that concurrent requests can not
+// exceed the limit, and the concurrent requests of the same DID get the
+// result of the first one. The rejections are returned as *Error.
+func (r *Registrar) record(db data.MasterQ, log *logan.Entry, reg documentRegistration) (*Result, error) {
+	documentHashStr := *reg.issuance.DocumentHash
+	userDID := reg.issuance.UserDID
+
+	var (
+		result    *Result
+		rejection *Error
+	)
+	if err := db.Transaction(func(db data.MasterQ) error {
+		document, err := LockDocument(db, documentHashStr, r.cfg.MultiAccPolicyVersion)
+		if err != nil {
+			return errors.Wrap(err, "failed to lock document")
+		}
+
+		// the request of the DID serialized before this one could have
+		// recorded the issuance after the check of Register
+		result, err = existingResult(db, userDID, reg.issuance.CredentialType)
+		if err != nil {
+			return err
+		}
+
+		if result != nil {
+			return nil
+		}
+
+		if document.IsBanned {
+			rejection = documentBanned(document.BanReason, r.cfg.ExposeBanDetails)
+			return rejection
+		}
+
+		// the credentials of other types issued to the DID for the same
+		// document do not register another account
+		registered, err := isRegistered(db, documentHashStr, userDID)
+		if err != nil {
+			return errors.Wrap(err, "failed to check whether the DID is registered with the document")
+		}
+
+		if reg.reregistration {
+			previous, err := lastActiveClaim(db, documentHashStr, reg.issuance.CredentialType)
+			if err != nil {
+				return errors.Wrap(err, "failed to get previous claim")
+			}
+
+			if previous == nil {
+				rejection = badRequest(validation.Errors{
+					"/data/reregistration": errNothingToReregister,
+				})
+				return rejection
+			}
+
+			if wait := time.Until(previous.CreatedAt.Add(r.cfg.ReregistrationCooldown)); wait > 0 {
+				rejection = reregistrationCooldown(wait)
+				return rejection
+			}
+
+			if err := revokeForReregistration(db, previous, userDID); err != nil {
+				return errors.Wrap(err, "failed to revoke previous claim")
+			}
+
+			log = log.WithField("revoked_claim_id", previous.ID.String())
+		} else if !registered && document.RegistrationCount > 0 {
+			count := document.RegistrationCount
+			allowed := multiAccLimit(reg.multiAccSecret, reg.documentHash, r.cfg.MultiAccMinLimit, r.cfg.MultiAccMaxLimit)
+			if count >= allowed {
+				if err := banForMultiAccount(db, documentHashStr, count, allowed, r.cfg.MultiAccPolicyVersion); err != nil {
+					return err
+				}
+
+				log.Infof("user of the provided document was banned for registering %d accounts, allowed is %d", count, allowed)
+				// the ban must be committed, so the transaction is not failed
+				rejection = tooManyIdentities(count, allowed, r.cfg.ExposeBanDetails)
+				return nil
+			}
+		}
+
+		// the credential is created after the commit, so that the issuer is
+		// not called while the document is locked
+		issuance, err := db.Issuance().Insert(r.outbox.NewLeasedIssuance(reg.issuance))
+		if err != nil {
+			return errors.Wrap(err, "failed to insert issuance")
+		}
+
+		result = &Result{Issuance: issuance, Leased: true}
+
+		// re-registration moves the existing claim and the DID registered
+		// with the document is already counted, so neither is counted
+		// towards the multi-account limit
+		if reg.reregistration || registered {
+			return nil
+		}
+
+		err = db.Document().FilterBy("document_hash", documentHashStr).Update(map[string]any{
+			"registration_count": document.RegistrationCount + 1,
+			"policy_version":     r.cfg.MultiAccPolicyVersion,
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to update document registration count")
+		}
+
+		return nil
+	}); err != nil {
+		if rejection != nil {
+			return nil, rejection
+		}
+		return nil, errors.Wrap(err, "failed to execute SQL transaction")
+	}
+
+	if rejection != nil {
+		return nil, rejection
+	}
+
+	return result, nil
+}
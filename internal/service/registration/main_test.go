@@ -0,0 +1,221 @@
+package registration
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rarimo/passport-identity-provider/internal/assets"
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/data/pg"
+	"github.com/rarimo/passport-identity-provider/internal/service/outbox"
+	migrate "github.com/rubenv/sql-migrate"
+	"gitlab.com/distributed_lab/kit/pgdb"
+	"gitlab.com/distributed_lab/logan/v3"
+)
+
+// newTestDB returns the queries of the migrated database from PGDB_CONN_URL,
+// the test is skipped if it is not set
+func newTestDB(t *testing.T) data.MasterQ {
+	t.Helper()
+
+	connURL := os.Getenv("PGDB_CONN_URL")
+	if connURL == "" {
+		t.Skip("skipping, PGDB_CONN_URL not set")
+	}
+
+	db, err := pgdb.Open(pgdb.Opts{
+		URL:                connURL,
+		MaxOpenConnections: 16,
+		MaxIdleConnections: 16,
+	})
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	t.Cleanup(func() {
+		db.RawDB().Close()
+	})
+
+	migrations := &migrate.EmbedFileSystemMigrationSource{
+		FileSystem: assets.Migrations,
+		Root:       "migrations",
+	}
+	if _, err := migrate.Exec(db.RawDB(), "postgres", migrations, migrate.Up); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	return pg.NewMasterQ(db)
+}
+
+// recordConcurrently records the registrations of the new document with the
+// DIDs returned for the request numbers, the results are in the request order
+func recordConcurrently(
+	t *testing.T, db data.MasterQ, registrar *Registrar, requests int, userDID func(i int) string,
+) (string, []*Result, []error) {
+	t.Helper()
+
+	log := logan.New().Out(io.Discard)
+
+	// a new document every run, so the database does not need to be clean
+	documentHash := big.NewInt(time.Now().UnixNano())
+	documentHashStr := documentHash.String()
+
+	var (
+		wg      sync.WaitGroup
+		results = make([]*Result, requests)
+		errs    = make([]error, requests)
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// the requests share the queries, the same way the workers do
+			results[i], errs[i] = registrar.record(db, log, documentRegistration{
+				issuance: data.Issuance{
+					UserDID:          userDID(i),
+					CredentialType:   "VotingCredential",
+					DocumentHash:     &documentHashStr,
+					IssuingAuthority: 1,
+					Nullifier:        fmt.Sprintf("%s%03d", documentHashStr, i),
+					Salt:             "1",
+				},
+				documentHash:   documentHash,
+				multiAccSecret: []byte("secret"),
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	return documentHashStr, results, errs
+}
+
+func TestRecordConcurrentMultiAccountLimit(t *testing.T) {
+	const (
+		limit    = 3
+		requests = 20
+	)
+
+	db := newTestDB(t)
+	registrar := &Registrar{
+		cfg: &config.VerifierConfig{
+			MultiAccMinLimit:      limit,
+			MultiAccMaxLimit:      limit,
+			MultiAccPolicyVersion: 1,
+		},
+		outbox: outbox.New(nil, nil, nil, &config.OutboxConfig{
+			Lease:         time.Minute,
+			MinRetryDelay: time.Second,
+			MaxRetryDelay: time.Minute,
+		}),
+	}
+
+	t.Run("different DIDs", func(t *testing.T) {
+		prefix := fmt.Sprintf("did:iden3:readonly:%d", time.Now().UnixNano())
+		documentHash, _, errs := recordConcurrently(t, db, registrar, requests, func(i int) string {
+			return fmt.Sprintf("%s%03d", prefix, i)
+		})
+
+		results := make(map[string]int)
+		for i, err := range errs {
+			result := "recorded"
+			if err != nil {
+				rejection, ok := err.(*Error)
+				if !ok {
+					t.Fatalf("request %d failed: %v", i, err)
+				}
+				result = rejection.Code()
+			}
+			results[result]++
+		}
+
+		// the request seeing the limit reached bans the document, the ones
+		// serialized after it see the ban
+		expected := map[string]int{
+			"recorded":            limit,
+			CodeTooManyIdentities: 1,
+			CodeDocumentBanned:    requests - limit - 1,
+		}
+		for result, count := range expected {
+			if results[result] != count {
+				t.Errorf("expected %d %s requests, got %d", count, result, results[result])
+			}
+		}
+
+		document, err := db.Document().FilterBy("document_hash", documentHash).Get()
+		if err != nil {
+			t.Fatalf("failed to get document: %v", err)
+		}
+
+		if document.RegistrationCount != limit {
+			t.Errorf("expected registration count %d, got %d", limit, document.RegistrationCount)
+		}
+
+		if !document.IsBanned {
+			t.Error("expected document to be banned")
+		}
+
+		issuances, err := db.Issuance().FilterBy("document_hash", documentHash).Select()
+		if err != nil {
+			t.Fatalf("failed to select issuances: %v", err)
+		}
+
+		if len(issuances) != limit {
+			t.Errorf("expected %d issuances, got %d", limit, len(issuances))
+		}
+	})
+
+	t.Run("same DID", func(t *testing.T) {
+		userDID := fmt.Sprintf("did:iden3:readonly:%d", time.Now().UnixNano())
+		documentHash, results, errs := recordConcurrently(t, db, registrar, requests, func(int) string {
+			return userDID
+		})
+
+		// the first request records the issuance, the ones serialized after
+		// it get the same issuance, which is processed by the first one
+		var leased *data.Issuance
+		for i, result := range results {
+			if errs[i] != nil {
+				t.Fatalf("request %d failed: %v", i, errs[i])
+			}
+			if result.Issuance == nil {
+				t.Fatalf("request %d: expected issuance", i)
+			}
+			if !result.Leased {
+				continue
+			}
+			if leased != nil {
+				t.Fatalf("request %d: expected single leased issuance, got %d and %d", i, leased.ID, result.Issuance.ID)
+			}
+			leased = result.Issuance
+		}
+
+		if leased == nil {
+			t.Fatal("expected leased issuance")
+		}
+
+		for i, result := range results {
+			if result.Issuance.ID != leased.ID {
+				t.Errorf("request %d: expected issuance %d, got %d", i, leased.ID, result.Issuance.ID)
+			}
+		}
+
+		document, err := db.Document().FilterBy("document_hash", documentHash).Get()
+		if err != nil {
+			t.Fatalf("failed to get document: %v", err)
+		}
+
+		if document.RegistrationCount != 1 {
+			t.Errorf("expected registration count 1, got %d", document.RegistrationCount)
+		}
+
+		if document.IsBanned {
+			t.Error("expected document not to be banned")
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package registration
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// multiAccLimit returns the amount of identities allowed for the document in
+// [minLimit, maxLimit]. The limit is derived from the HMAC of the document
+// hash, so it is stable for the document and can be recomputed for audit by
+// the secret holder, but it can not be predicted without the secret.
+func multiAccLimit(secret []byte, documentHash *big.Int, minLimit, maxLimit int) int {
+	if maxLimit <= minLimit {
+		return minLimit
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(documentHash.Bytes())
+
+	limitRange := big.NewInt(int64(maxLimit - minLimit + 1))
+	offset := new(big.Int).Mod(new(big.Int).SetBytes(mac.Sum(nil)), limitRange)
+
+	return minLimit + int(offset.Int64())
+}
+
+// LockDocument registers the document if it is seen for the first time and
+// locks its row until the end of the transaction
+func LockDocument(db data.MasterQ, documentHash string, policyVersion int) (*data.Document, error) {
+	err := db.Document().InsertIfNotExists(data.Document{
+		DocumentHash:  documentHash,
+		PolicyVersion: policyVersion,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to insert document")
+	}
+
+	document, err := db.Document().FilterBy("document_hash", documentHash).ForUpdate().Get()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get document")
+	}
+	if document == nil {
+		return nil, errors.New("document is missing after insert")
+	}
+
+	return document, nil
+}
+
+// banForMultiAccount bans the document that exceeded the multi-account limit
+// and schedules the revocation of its claims
+func banForMultiAccount(db data.MasterQ, documentHash string, count, allowed, policyVersion int) error {
+	reason := fmt.Sprintf("multi-account limit exceeded: %d registered, %d allowed", count, allowed)
+	err := db.Document().FilterBy("document_hash", documentHash).Update(map[string]any{
+		"is_banned":      true,
+		"ban_reason":     reason,
+		"policy_version": policyVersion,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to ban user")
+	}
+
+	if err := db.ClaimRevocation().ScheduleForDocument(documentHash); err != nil {
+		return errors.Wrap(err, "failed to schedule claims revocation")
+	}
+
+	if err := db.AuditLog().Insert(data.AuditLogEntry{
+		DocumentHash: documentHash,
+		Action:       data.AuditActionBan,
+		Reason:       reason,
+		Actor:        data.AuditActorSystem,
+	}); err != nil {
+		return errors.Wrap(err, "failed to insert audit log entry")
+	}
+
+	return nil
+}
+
+// isRegistered reports whether the DID already has an active claim or a
+// pending issuance of any credential type for the document
+func isRegistered(db data.MasterQ, documentHash, userDID string) (bool, error) {
+	claim, err := db.Claim().
+		FilterBy("document_hash", documentHash).
+		FilterBy("user_did", userDID).
+		FilterBy("revoked_at", nil).
+		FilterBy("expired_at", nil).
+		Get()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get claim of the DID")
+	}
+
+	if claim != nil {
+		return true, nil
+	}
+
+	issuance, err := db.Issuance().
+		FilterBy("document_hash", documentHash).
+		FilterBy("user_did", userDID).
+		// the failed issuances were counted as well, so retrying the
+		// registration does not count the DID again
+		FilterBy("status", []string{data.IssuanceStatusPending, data.IssuanceStatusFailed}).
+		Get()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get issuance of the DID")
+	}
+
+	return issuance != nil, nil
+}
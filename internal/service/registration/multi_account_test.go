@@ -0,0 +1,65 @@
+package registration
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestMultiAccLimit(t *testing.T) {
+	secret := []byte("secret")
+
+	tests := []struct {
+		name               string
+		minLimit, maxLimit int
+	}{
+		{"range", 2, 5},
+		{"wide range", 1, 100},
+		{"min equals max", 3, 3},
+		{"max below min", 4, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen := make(map[int]bool)
+			for i := int64(0); i < 1000; i++ {
+				documentHash := big.NewInt(i)
+
+				limit := multiAccLimit(secret, documentHash, tt.minLimit, tt.maxLimit)
+				if tt.maxLimit <= tt.minLimit {
+					if limit != tt.minLimit {
+						t.Fatalf("expected limit %d, got %d", tt.minLimit, limit)
+					}
+					continue
+				}
+
+				if limit < tt.minLimit || limit > tt.maxLimit {
+					t.Fatalf("limit %d is out of [%d, %d]", limit, tt.minLimit, tt.maxLimit)
+				}
+
+				if again := multiAccLimit(secret, documentHash, tt.minLimit, tt.maxLimit); again != limit {
+					t.Fatalf("expected the same limit %d for the document, got %d", limit, again)
+				}
+				seen[limit] = true
+			}
+
+			// the limits are spread over the whole range
+			if tt.maxLimit > tt.minLimit && tt.maxLimit-tt.minLimit < 10 && len(seen) != tt.maxLimit-tt.minLimit+1 {
+				t.Errorf("expected every limit in [%d, %d], got %v", tt.minLimit, tt.maxLimit, seen)
+			}
+		})
+	}
+}
+
+func TestMultiAccLimitDependsOnSecret(t *testing.T) {
+	// the limits of the same documents differ for another secret
+	differs := false
+	for i := int64(0); i < 100 && !differs; i++ {
+		documentHash := big.NewInt(i)
+		differs = multiAccLimit([]byte("first"), documentHash, 1, 100) !=
+			multiAccLimit([]byte("second"), documentHash, 1, 100)
+	}
+
+	if !differs {
+		t.Error("expected limits to depend on the secret")
+	}
+}
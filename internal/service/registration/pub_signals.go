@@ -0,0 +1,147 @@
+package registration
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/service/api/requests"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+func validatePubSignals(
+	agePolicy config.AgePolicy, requestData requests.CreateIdentityRequestData, dg1 []byte,
+) error {
+	if err := validatePubSignalsDG1Hash(dg1, requestData.ZKProof.PubSignals); err != nil {
+		return errors.Wrap(err, "failed to validate DG1 hash")
+	}
+
+	if err := validatePubSignalsCurrentDate(requestData.ZKProof.PubSignals); err != nil {
+		return fmt.Errorf("invalid current date: %w", err)
+	}
+
+	if err := validatePubSignalsAge(agePolicy, requestData.ZKProof.PubSignals); err != nil {
+		return errors.Wrap(err, "failed to validate pub signals age")
+	}
+
+	return nil
+}
+
+func validatePubSignalsDG1Hash(dg1 []byte, pubSignals []string) error {
+	ints, err := stringsToArrayBigInt([]string{pubSignals[0], pubSignals[1]})
+	if err != nil {
+		return errors.Wrap(err, "failed to convert strings to big integers")
+	}
+
+	hashBytes := make([]byte, 0)
+	hashBytes = append(hashBytes, ints[0].Bytes()...)
+	hashBytes = append(hashBytes, ints[1].Bytes()...)
+
+	if !bytes.Equal(dg1, hashBytes) {
+		return errors.New("encapsulated data and proof pub signals hashes are different")
+	}
+
+	return nil
+}
+
+func validatePubSignalsCurrentDate(pubSignals []string) error {
+	year, err := strconv.Atoi(pubSignals[3])
+	if err != nil {
+		return fmt.Errorf("invalid year: %w", err)
+	}
+
+	month, err := strconv.Atoi(pubSignals[4])
+	if err != nil {
+		return fmt.Errorf("invalid month: %w", err)
+	}
+
+	day, err := strconv.Atoi(pubSignals[5])
+	if err != nil {
+		return fmt.Errorf("invalid day: %w", err)
+	}
+
+	currentTime := time.Now().UTC()
+
+	if currentTime.Year() != (2000 + year) {
+		return fmt.Errorf("invalid year, expected %d, got %d", currentTime.Year(), 2000+year)
+	}
+
+	if currentTime.Month() != time.Month(month) {
+		return fmt.Errorf("invalid month, expected %d, got %d", currentTime.Month(), month)
+	}
+
+	if currentTime.Day() != day {
+		return fmt.Errorf("invalid day, expected %d, got %d", currentTime.Day(), day)
+	}
+
+	return nil
+}
+
+func validatePubSignalsAge(agePolicy config.AgePolicy, pubSignals []string) error {
+	age, err := getAgeFromPubSignals(pubSignals)
+	if err != nil {
+		return err
+	}
+	if !agePolicy.Allows(age) {
+		return errors.New("invalid age")
+	}
+	return nil
+}
+
+func getAgeFromPubSignals(pubSignals []string) (int, error) {
+	age, err := strconv.Atoi(pubSignals[9])
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to convert pub input to int")
+	}
+	return age, nil
+}
+
+func getExpirationTimeFromPubSignals(pubSignals []string) (*time.Time, error) {
+	year, err := strconv.Atoi(pubSignals[6])
+	if err != nil {
+		return nil, fmt.Errorf("invalid year: %w", err)
+	}
+
+	month, err := strconv.Atoi(pubSignals[7])
+	if err != nil {
+		return nil, fmt.Errorf("invalid month: %w", err)
+	}
+
+	day, err := strconv.Atoi(pubSignals[8])
+	if err != nil {
+		return nil, fmt.Errorf("invalid day: %w", err)
+	}
+
+	expirationDate := time.Date(2000+year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+
+	return &expirationDate, nil
+}
+
+func stringsToArrayBigInt(publicSignals []string) ([]*big.Int, error) {
+	p := make([]*big.Int, 0, len(publicSignals))
+	for _, s := range publicSignals {
+		sb, err := stringToBigInt(s)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, sb)
+	}
+	return p, nil
+}
+
+func stringToBigInt(s string) (*big.Int, error) {
+	base := 10
+	if bytes.HasPrefix([]byte(s), []byte("0x")) {
+		base = 16
+		s = strings.TrimPrefix(s, "0x")
+	}
+	n, ok := new(big.Int).SetString(s, base)
+	if !ok {
+		return nil, fmt.Errorf("can not parse string to *big.Int: %s", s)
+	}
+	return n, nil
+}
@@ -0,0 +1,57 @@
+package registration
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+var errNothingToReregister = errors.New("document has no active claim to re-register")
+
+// lastActiveClaim returns the latest claim of the given credential type of
+// the document that was neither revoked nor expired, nil if there is none
+func lastActiveClaim(db data.MasterQ, documentHash, credentialType string) (*data.Claim, error) {
+	claim, err := db.Claim().
+		FilterBy("document_hash", documentHash).
+		FilterBy("credential_type", credentialType).
+		FilterBy("revoked_at", nil).
+		FilterBy("expired_at", nil).
+		OrderBy("created_at DESC").
+		Get()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get last active claim")
+	}
+
+	return claim, nil
+}
+
+// revokeForReregistration marks the previous document claim as revoked and
+// schedules its revocation in the issuer, which is done by the revoker after
+// the commit, so the issuer is not called while the document is locked. The
+// action is recorded in the audit log.
+func revokeForReregistration(db data.MasterQ, previous *data.Claim, newDID string) error {
+	err := db.Claim().FilterBy("id", previous.ID).Update(map[string]any{
+		"revoked_at": time.Now().UTC(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to mark previous claim as revoked")
+	}
+
+	if err := db.ClaimRevocation().ScheduleForClaims([]uuid.UUID{previous.ID}); err != nil {
+		return errors.Wrap(err, "failed to schedule previous claim revocation")
+	}
+
+	if err := db.AuditLog().Insert(data.AuditLogEntry{
+		DocumentHash: *previous.DocumentHash,
+		Action:       data.AuditActionReregister,
+		Reason:       fmt.Sprintf("claim %s of %s was moved to %s", previous.ID, previous.UserDID, newDID),
+		Actor:        data.AuditActorSystem,
+	}); err != nil {
+		return errors.Wrap(err, "failed to insert audit log entry")
+	}
+
+	return nil
+}
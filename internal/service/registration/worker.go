@@ -0,0 +1,204 @@
+package registration
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/api/requests"
+	"github.com/rarimo/passport-identity-provider/internal/service/outbox"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+	"gitlab.com/distributed_lab/running"
+)
+
+const (
+	failureCodeInvalidRequest = "invalid_request"
+	failureCodeInternal       = "internal_error"
+)
+
+// Worker processes the asynchronous registrations. A registration is leased
+// for a single attempt, so an attempt interrupted by a restart is retried once
+// the lease is over.
+type Worker struct {
+	log       *logan.Entry
+	q         data.MasterQ
+	registrar *Registrar
+	cfg       *config.RegistrationsConfig
+}
+
+func NewWorker(log *logan.Entry, q data.MasterQ, registrar *Registrar, cfg *config.RegistrationsConfig) *Worker {
+	return &Worker{
+		log:       log,
+		q:         q,
+		registrar: registrar,
+		cfg:       cfg,
+	}
+}
+
+// Run processes the registrations until ctx is done
+func (w *Worker) Run(ctx context.Context) {
+	running.WithBackOff(ctx, w.log, "registrations", w.processPending,
+		w.cfg.Period, w.cfg.Period, w.cfg.Lease)
+}
+
+func (w *Worker) processPending(ctx context.Context) error {
+	var registrations []data.Registration
+	err := w.q.Transaction(func(db data.MasterQ) error {
+		var err error
+		registrations, err = db.Registration().
+			FilterDue(time.Now().UTC()).
+			Limit(w.cfg.BatchSize).
+			ForUpdateSkipLocked().
+			Select()
+		if err != nil {
+			return errors.Wrap(err, "failed to select pending registrations")
+		}
+
+		for i := range registrations {
+			registrations[i].Attempts++
+			err := db.Registration().FilterBy("id", registrations[i].ID).Update(map[string]any{
+				"attempts":        registrations[i].Attempts,
+				"next_attempt_at": time.Now().UTC().Add(w.cfg.Lease),
+				"updated_at":      time.Now().UTC(),
+			})
+			if err != nil {
+				return errors.Wrap(err, "failed to lease registration")
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to lease pending registrations")
+	}
+
+	// the registrations are leased, so a failed one is left until its lease
+	// is over and the others are processed
+	for _, registration := range registrations {
+		if err := w.process(ctx, registration); err != nil {
+			w.log.WithError(err).WithField("registration_id", registration.ID.String()).
+				Error("failed to process registration")
+		}
+	}
+
+	return nil
+}
+
+func (w *Worker) process(ctx context.Context, registration data.Registration) error {
+	log := w.log.WithFields(logan.F{
+		"registration_id": registration.ID.String(),
+		"attempts":        registration.Attempts,
+	})
+
+	var req requests.CreateIdentityRequestData
+	if registration.Request == nil {
+		return w.fail(registration, failureCodeInvalidRequest, "Request is missing")
+	}
+	if err := json.Unmarshal([]byte(*registration.Request), &req); err != nil {
+		return w.fail(registration, failureCodeInvalidRequest, "Request is malformed")
+	}
+
+	result, err := w.registrar.Register(ctx, w.q, log, req)
+	if err != nil {
+		rejection, ok := err.(*Error)
+		switch {
+		case ok && !rejection.Transient():
+			// the re-registration cool-down is not waited out either, the
+			// client repeats the request once it is over
+			log.WithError(err).Debug("registration is rejected")
+			return w.fail(registration, rejection.Code(), rejection.Reason())
+		case registration.Attempts >= w.cfg.MaxAttempts:
+			log.WithError(err).Error("registration attempts are exhausted")
+			return w.fail(registration, failureCodeInternal, "Registration could not be processed")
+		case ok:
+			log.WithError(err).Debug("registration is postponed")
+			return w.postpone(registration, rejection.RetryAfter)
+		default:
+			// the registration is retried once the lease is over
+			log.WithError(err).Warn("registration attempt failed")
+			return nil
+		}
+	}
+
+	if result.Claim != nil {
+		return w.update(registration, map[string]any{
+			"status":   data.RegistrationStatusIssued,
+			"claim_id": result.Claim.ID,
+			"request":  nil,
+		})
+	}
+
+	if err := w.update(registration, map[string]any{
+		"status":      data.RegistrationStatusIssuing,
+		"issuance_id": result.Issuance.ID,
+		"request":     nil,
+	}); err != nil {
+		return err
+	}
+
+	if result.Leased {
+		// failed attempts are retried by the outbox until the issuance fails
+		if _, err := w.registrar.outbox.Process(w.q, *result.Issuance); err != nil {
+			log.WithError(err).Warn("failed to issue credential")
+		}
+	}
+
+	// the issuance could be completed or failed before it was linked to the
+	// registration, in which case the outbox did not update it
+	issuance, err := w.q.Issuance().FilterBy("id", result.Issuance.ID).Get()
+	if err != nil {
+		return errors.Wrap(err, "failed to get issuance")
+	}
+	if issuance == nil {
+		return nil
+	}
+
+	switch issuance.Status {
+	case data.IssuanceStatusIssued:
+		return w.update(registration, map[string]any{
+			"status":   data.RegistrationStatusIssued,
+			"claim_id": issuance.ClaimID,
+		})
+	case data.IssuanceStatusFailed:
+		log.WithField("last_error", issuance.LastError).Warn("issuance failed")
+		code, reason := outbox.Failure(*issuance)
+		return w.fail(registration, code, reason)
+	}
+
+	return nil
+}
+
+func (w *Worker) fail(registration data.Registration, code, reason string) error {
+	if code == "" {
+		code = failureCodeInvalidRequest
+	}
+
+	return w.update(registration, map[string]any{
+		"status":         data.RegistrationStatusFailed,
+		"failure_code":   code,
+		"failure_reason": reason,
+		"request":        nil,
+	})
+}
+
+func (w *Worker) postpone(registration data.Registration, delay time.Duration) error {
+	return w.update(registration, map[string]any{
+		"next_attempt_at": time.Now().UTC().Add(delay),
+	})
+}
+
+func (w *Worker) update(registration data.Registration, fields map[string]any) error {
+	fields["updated_at"] = time.Now().UTC()
+
+	err := w.q.Registration().FilterBy("id", registration.ID).Update(fields)
+	if err != nil {
+		return errors.Wrap(err, "failed to update registration", logan.F{
+			"registration_id": registration.ID.String(),
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rarimo/passport-identity-provider/internal/service/verifier"
+)
+
+// reloadKeysOnSignal re-reads verification keys from disk on SIGHUP, so the
+// keys can be rotated without restarting the service
+func (s *service) reloadKeysOnSignal(v *verifier.Verifier) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	for range signals {
+		if err := v.ReloadKeys(); err != nil {
+			s.log.WithError(err).Error("failed to reload verification keys")
+			continue
+		}
+		s.log.Info("verification keys reloaded")
+	}
+}
@@ -0,0 +1,190 @@
+package revoker
+
+import (
+	"context"
+	"time"
+
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+	"gitlab.com/distributed_lab/running"
+)
+
+// Revoker revokes the claims of banned documents in the issuer. Every claim is
+// retried with an exponential delay until the issuer reports its credential
+// as revoked.
+type Revoker struct {
+	log     *logan.Entry
+	q       data.MasterQ
+	issuers *issuer.Registry
+	cfg     *config.RevokerConfig
+}
+
+func New(log *logan.Entry, q data.MasterQ, issuers *issuer.Registry, cfg *config.RevokerConfig) *Revoker {
+	return &Revoker{
+		log:     log,
+		q:       q,
+		issuers: issuers,
+		cfg:     cfg,
+	}
+}
+
+// Run processes the pending revocations until ctx is done
+func (r *Revoker) Run(ctx context.Context) {
+	running.WithBackOff(ctx, r.log, "claim-revoker", r.processPending,
+		r.cfg.Period, r.cfg.MinRetryDelay, r.cfg.MaxRetryDelay)
+}
+
+func (r *Revoker) processPending(_ context.Context) error {
+	var revocations []data.ClaimRevocation
+	err := r.q.Transaction(func(db data.MasterQ) error {
+		var err error
+		revocations, err = db.ClaimRevocation().
+			FilterDue(time.Now().UTC()).
+			Limit(r.cfg.BatchSize).
+			ForUpdateSkipLocked().
+			Select()
+		if err != nil {
+			return errors.Wrap(err, "failed to select pending revocations")
+		}
+
+		for i := range revocations {
+			if err := r.lease(db, &revocations[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to lease pending revocations")
+	}
+
+	// the revocations are leased, so a failed one is left until its lease
+	// is over and the others are processed
+	for _, revocation := range revocations {
+		if err := r.process(revocation); err != nil {
+			r.log.WithError(err).WithField("claim_id", revocation.ClaimID.String()).
+				Error("failed to process revocation")
+		}
+	}
+
+	return nil
+}
+
+// lease counts the attempt and reserves the revocation for it, so that other
+// instances skip it until the attempt is over
+func (r *Revoker) lease(db data.MasterQ, revocation *data.ClaimRevocation) error {
+	revocation.Attempts++
+	revocation.NextAttemptAt = time.Now().UTC().Add(r.cfg.Lease + r.retryDelay(revocation.Attempts-1))
+
+	err := db.ClaimRevocation().FilterBy("claim_id", revocation.ClaimID).Update(map[string]any{
+		"attempts":        revocation.Attempts,
+		"next_attempt_at": revocation.NextAttemptAt,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to lease revocation", logan.F{"claim_id": revocation.ClaimID.String()})
+	}
+
+	return nil
+}
+
+// process checks the credential state in the issuer and either confirms the
+// revocation or requests it and schedules the next check
+func (r *Revoker) process(revocation data.ClaimRevocation) error {
+	log := r.log.WithField("claim_id", revocation.ClaimID.String())
+
+	claim, err := r.q.Claim().FilterBy("id", revocation.ClaimID).Get()
+	if err != nil {
+		return errors.Wrap(err, "failed to get claim")
+	}
+	if claim == nil {
+		return errors.New("claim of the revocation is not found")
+	}
+
+	iss, ok := r.issuers.ByDID(claim.IssuerDID)
+	if !ok {
+		return r.scheduleRetry(revocation, errors.From(errors.New("issuer of the claim is not configured"), logan.F{
+			"issuer_did": claim.IssuerDID,
+		}))
+	}
+
+	cred, err := iss.GetCredential(revocation.ClaimID)
+	if err != nil {
+		return r.scheduleRetry(revocation, errors.Wrap(err, "failed to get credential"))
+	}
+
+	if cred.Revoked {
+		now := time.Now().UTC()
+		err = r.q.Transaction(func(db data.MasterQ) error {
+			if err := db.ClaimRevocation().FilterBy("claim_id", revocation.ClaimID).Update(map[string]any{
+				"confirmed_at": now,
+				"last_error":   nil,
+			}); err != nil {
+				return errors.Wrap(err, "failed to confirm revocation")
+			}
+
+			if err := db.Claim().FilterBy("id", revocation.ClaimID).Update(map[string]any{
+				"revoked_at": now,
+			}); err != nil {
+				return errors.Wrap(err, "failed to mark claim as revoked")
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		log.Info("claim revocation confirmed")
+		return nil
+	}
+
+	if err := iss.RevokeClaim(cred.RevNonce); err != nil {
+		return r.scheduleRetry(revocation, errors.Wrap(err, "failed to revoke claim"))
+	}
+
+	log.Debug("claim revocation requested, waiting for confirmation")
+	// the revocation is confirmed on the next attempt
+	return r.scheduleRetry(revocation, nil)
+}
+
+// scheduleRetry ends the lease of the attempt and records its error, if any,
+// the error is only logged so that other claims are not blocked by a single
+// failing one
+func (r *Revoker) scheduleRetry(revocation data.ClaimRevocation, cause error) error {
+	fields := map[string]any{
+		"next_attempt_at": time.Now().UTC().Add(r.retryDelay(revocation.Attempts - 1)),
+		"last_error":      nil,
+	}
+
+	if cause != nil {
+		r.log.WithError(cause).WithFields(logan.F{
+			"claim_id": revocation.ClaimID.String(),
+			"attempts": revocation.Attempts,
+		}).Warn("claim revocation attempt failed")
+		fields["last_error"] = cause.Error()
+	}
+
+	err := r.q.ClaimRevocation().FilterBy("claim_id", revocation.ClaimID).Update(fields)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule next revocation attempt")
+	}
+
+	return nil
+}
+
+func (r *Revoker) retryDelay(attempts int) time.Duration {
+	delay := r.cfg.MinRetryDelay
+	for i := 0; i < attempts && delay < r.cfg.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > r.cfg.MaxRetryDelay {
+		delay = r.cfg.MaxRetryDelay
+	}
+
+	return delay
+}
@@ -1,16 +1,30 @@
 package service
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/go-chi/chi"
 	stateabi "github.com/iden3/contracts-abi/state/go/abi"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/data"
 	"github.com/rarimo/passport-identity-provider/internal/data/pg"
 	"github.com/rarimo/passport-identity-provider/internal/service/api"
 	"github.com/rarimo/passport-identity-provider/internal/service/api/handlers"
+	"github.com/rarimo/passport-identity-provider/internal/service/expirer"
+	"github.com/rarimo/passport-identity-provider/internal/service/gist"
 	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuer/embedded"
+	"github.com/rarimo/passport-identity-provider/internal/service/outbox"
+	"github.com/rarimo/passport-identity-provider/internal/service/registration"
+	"github.com/rarimo/passport-identity-provider/internal/service/revoker"
 	"github.com/rarimo/passport-identity-provider/internal/service/vault"
+	"github.com/rarimo/passport-identity-provider/internal/service/verifier"
+	"github.com/rarimo/passport-identity-provider/internal/service/watcher"
 	"gitlab.com/distributed_lab/ape"
+	"gitlab.com/distributed_lab/logan/v3"
 )
 
 func (s *service) router() chi.Router {
@@ -24,15 +38,79 @@ func (s *service) router() chi.Router {
 		s.log.WithError(err).Fatal("failed to init state contract")
 	}
 
+	gistProvider := gist.New(s.cfg.Log().WithField("service", "gist"), ethCli, stateContract, s.cfg.GistConfig())
+	go gistProvider.Run(context.Background())
+
 	vaultClient, err := vault.NewVaultClient(s.cfg.VaultConfig())
 	if err != nil {
 		s.log.WithError(err).Fatal("failed to init new vault client")
 	}
 
-	issuerLogin, issuerPassword, err := vaultClient.IssuerAuthData()
+	proofVerifier, err := verifier.New(s.cfg.Log().WithField("service", "verifier"), s.cfg.VerifierConfig())
+	if err != nil {
+		s.log.WithError(err).Fatal("failed to init proof verifier")
+	}
+	proofVerifier.Run(context.Background())
+	go s.reloadKeysOnSignal(proofVerifier)
+
+	adminTokens, err := vaultClient.AdminTokens()
 	if err != nil {
-		s.log.WithError(err).Fatal("failed to get issuer auth data from the vault")
+		s.log.WithError(err).Fatal("failed to get admin tokens from the vault")
+	}
+	if len(adminTokens) == 0 {
+		s.log.Warn("no admin tokens in the vault, admin API is disabled")
+	}
+
+	masterQ := pg.NewMasterQ(s.cfg.DB())
+	iss := s.newIssuer(
+		s.cfg.Log().WithField("service", "issuer"),
+		s.cfg.IssuerConfig(),
+		masterQ,
+		stateContract,
+		vaultClient,
+		"",
+	)
+
+	issuerProfiles := make(map[string]*issuer.Issuer)
+	for _, profile := range s.cfg.IssuersConfig().Profiles {
+		issuerProfiles[profile.Name] = s.newIssuer(
+			s.cfg.Log().WithFields(logan.F{"service": "issuer", "profile": profile.Name}),
+			profile.Issuer,
+			masterQ,
+			stateContract,
+			vaultClient,
+			profile.VaultPath,
+		)
 	}
+	issuers := issuer.NewRegistry(iss, issuerProfiles, s.cfg.IssuersConfig().Rules)
+
+	go revoker.New(
+		s.cfg.Log().WithField("service", "revoker"),
+		masterQ.New(), issuers, s.cfg.RevokerConfig(),
+	).Run(context.Background())
+
+	issuanceOutbox := outbox.New(
+		s.cfg.Log().WithField("service", "outbox"),
+		masterQ.New(), issuers, s.cfg.OutboxConfig(),
+	)
+	go issuanceOutbox.Run(context.Background())
+
+	go expirer.New(
+		s.cfg.Log().WithField("service", "expirer"),
+		masterQ.New(), s.cfg.ExpirerConfig(),
+	).Run(context.Background())
+
+	go watcher.New(
+		s.cfg.Log().WithField("service", "watcher"),
+		masterQ.New(), issuers, stateContract, s.cfg.WatcherConfig(),
+	).Run(context.Background())
+
+	go registration.NewWorker(
+		s.cfg.Log().WithField("service", "registrations"),
+		masterQ.New(),
+		registration.New(s.cfg.VerifierConfig(), proofVerifier, issuers, vaultClient, issuanceOutbox),
+		s.cfg.RegistrationsConfig(),
+	).Run(context.Background())
 
 	r := chi.NewRouter()
 
@@ -41,24 +119,95 @@ func (s *service) router() chi.Router {
 		ape.LoganMiddleware(s.log),
 		ape.CtxMiddleware(
 			api.CtxLog(s.log),
-			api.CtxMasterQ(pg.NewMasterQ(s.cfg.DB())),
+			api.CtxMasterQ(masterQ),
 			api.CtxVerifierConfig(s.cfg.VerifierConfig()),
 			api.CtxStateContract(stateContract),
-			api.CtxIssuer(issuer.New(
-				s.cfg.Log().WithField("service", "issuer"),
-				s.cfg.IssuerConfig(),
-				issuerLogin, issuerPassword,
-			)),
+			api.CtxIssuers(issuers),
 			api.CtxVaultClient(vaultClient),
 			api.CtxEthClient(ethCli),
+			api.CtxVerifier(proofVerifier),
+			api.CtxOutbox(issuanceOutbox),
+			api.CtxGist(gistProvider),
 		),
 	)
+	r.Handle("/metrics", promhttp.Handler())
 	r.Route("/integrations/identity-provider-service", func(r chi.Router) {
 		r.Route("/v1", func(r chi.Router) {
 			r.Post("/create-identity", handlers.CreateIdentity)
+			r.Get("/claims/{id}", handlers.GetClaim)
+			r.Get("/claims/{id}/proof", handlers.GetClaimProof)
 			r.Get("/gist-data", handlers.GetGistData)
+			r.Get("/health", handlers.GetHealth)
+			r.Get("/registrations/{id}", handlers.GetRegistration)
+
+			// the admin API is not served without tokens
+			if len(adminTokens) == 0 {
+				return
+			}
+
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(api.AdminAuth(adminTokens))
+
+				r.Get("/documents/banned", handlers.ListBannedDocuments)
+				r.Get("/issuers/{did}/state-transition", handlers.GetStateTransition)
+				r.Route("/documents/{document_hash}", func(r chi.Router) {
+					r.Get("/claims", handlers.GetDocumentClaims)
+					r.Post("/ban", handlers.BanDocument)
+					r.Post("/unban", handlers.UnbanDocument)
+				})
+			})
 		})
 	})
 
 	return r
 }
+
+// newIssuer creates the issuer of the config mode, the embedded issuer
+// starts recording its state transitions. The Vault secret at vaultPath holds
+// the Issuer Node login and password or the embedded issuer key, unless the
+// config sets key_path. The issuer secret is used if vaultPath is empty.
+func (s *service) newIssuer(
+	log *logan.Entry,
+	cfg *config.IssuerConfig,
+	masterQ data.MasterQ,
+	state *stateabi.State,
+	vaultClient *vault.VaultClient,
+	vaultPath string,
+) *issuer.Issuer {
+	if cfg.Mode != config.IssuerModeEmbedded {
+		if vaultPath == "" {
+			vaultPath = vault.IssuerPath
+		}
+
+		login, password, err := vaultClient.IssuerAuthDataAt(vaultPath)
+		if err != nil {
+			log.WithError(err).Fatal("failed to get issuer auth data from the vault")
+		}
+
+		return issuer.New(log, cfg, s.cfg.CredentialsConfig(), login, password)
+	}
+
+	switch {
+	case cfg.KeyPath != "":
+		vaultPath = cfg.KeyPath
+	case vaultPath == "":
+		vaultPath = vault.IssuerPath
+	}
+
+	key, err := vaultClient.IssuerKeyAt(vaultPath)
+	if err != nil {
+		log.WithError(err).Fatal("failed to get issuer key from the vault")
+	}
+
+	identity, err := embedded.New(log, masterQ.New(), state, cfg.DID, key, cfg.TransitionPeriod)
+	if err != nil {
+		log.WithError(err).Fatal("failed to init embedded issuer")
+	}
+	iss, err := issuer.NewEmbedded(log, cfg, s.cfg.CredentialsConfig(), identity)
+	if err != nil {
+		log.WithError(err).Fatal("failed to init embedded issuer")
+	}
+	go identity.Run(context.Background())
+
+	return iss
+}
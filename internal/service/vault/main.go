@@ -2,16 +2,23 @@ package vault
 
 import (
 	"context"
+	"encoding/hex"
+	stderrors "errors"
 	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/iden3/go-iden3-crypto/babyjub"
 	"github.com/rarimo/passport-identity-provider/internal/config"
 	"gitlab.com/distributed_lab/figure/v3"
 	"gitlab.com/distributed_lab/logan/v3/errors"
 	"math/big"
+	"strings"
 )
 
+// IssuerPath is the secret of the default issuer
+const IssuerPath = "issuer"
+
 const (
-	vaultIssuerPath   = "issuer"
 	vaultVerifierPath = "verifier"
+	vaultAdminPath    = "admin"
 )
 
 type VaultClient struct {
@@ -37,12 +44,18 @@ func NewVaultClient(cfg *config.VaultConfig) (*VaultClient, error) {
 }
 
 func (v *VaultClient) IssuerAuthData() (string, string, error) {
+	return v.IssuerAuthDataAt(IssuerPath)
+}
+
+// IssuerAuthDataAt returns the issuer login and password stored in the given
+// secret, it is used for the additional issuer profiles
+func (v *VaultClient) IssuerAuthDataAt(path string) (string, string, error) {
 	conf := struct {
 		IssuerLogin    string `fig:"login,required"`
 		IssuerPassword string `fig:"password,required"`
 	}{}
 
-	secret, err := v.client.KVv2(v.mountPath).Get(context.Background(), vaultIssuerPath)
+	secret, err := v.client.KVv2(v.mountPath).Get(context.Background(), path)
 	if err != nil {
 		return "", "", errors.Wrap(err, "failed to get secret")
 	}
@@ -58,6 +71,40 @@ func (v *VaultClient) IssuerAuthData() (string, string, error) {
 	return conf.IssuerLogin, conf.IssuerPassword, nil
 }
 
+// IssuerKeyAt returns the BabyJubJub private key of the embedded issuer
+// stored hex encoded in the given secret
+func (v *VaultClient) IssuerKeyAt(path string) (babyjub.PrivateKey, error) {
+	conf := struct {
+		PrivateKey string `fig:"private_key,required"`
+	}{}
+
+	secret, err := v.client.KVv2(v.mountPath).Get(context.Background(), path)
+	if err != nil {
+		return babyjub.PrivateKey{}, errors.Wrap(err, "failed to get secret")
+	}
+
+	if err := figure.
+		Out(&conf).
+		With(figure.BaseHooks).
+		From(secret.Data).
+		Please(); err != nil {
+		return babyjub.PrivateKey{}, errors.Wrap(err, "failed to figure out")
+	}
+
+	raw, err := hex.DecodeString(strings.TrimPrefix(conf.PrivateKey, "0x"))
+	if err != nil {
+		return babyjub.PrivateKey{}, errors.Wrap(err, "failed to decode private key")
+	}
+
+	var key babyjub.PrivateKey
+	if len(raw) != len(key) {
+		return babyjub.PrivateKey{}, errors.New("private key must be 32 bytes long")
+	}
+	copy(key[:], raw)
+
+	return key, nil
+}
+
 func (v *VaultClient) Blinder() (*big.Int, error) {
 	conf := struct {
 		Blinder string `fig:"blinder,required"`
@@ -83,3 +130,49 @@ func (v *VaultClient) Blinder() (*big.Int, error) {
 
 	return blinder, nil
 }
+
+func (v *VaultClient) MultiAccSecret() ([]byte, error) {
+	conf := struct {
+		MultiAccSecret string `fig:"multi_acc_secret,required"`
+	}{}
+
+	secret, err := v.client.KVv2(v.mountPath).Get(context.Background(), vaultVerifierPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get secret")
+	}
+
+	if err := figure.
+		Out(&conf).
+		With(figure.BaseHooks).
+		From(secret.Data).
+		Please(); err != nil {
+		return nil, errors.Wrap(err, "failed to figure out")
+	}
+
+	return []byte(conf.MultiAccSecret), nil
+}
+
+// AdminTokens returns the admin API tokens by the admin names, every key of
+// the secret is the name and the value is the token. The secret is optional,
+// no tokens are returned if it does not exist.
+func (v *VaultClient) AdminTokens() (map[string]string, error) {
+	tokens := make(map[string]string)
+
+	secret, err := v.client.KVv2(v.mountPath).Get(context.Background(), vaultAdminPath)
+	if err != nil {
+		if stderrors.Is(err, vaultapi.ErrSecretNotFound) {
+			return tokens, nil
+		}
+		return nil, errors.Wrap(err, "failed to get secret")
+	}
+
+	if err := figure.
+		Out(&tokens).
+		With(figure.BaseHooks).
+		From(secret.Data).
+		Please(); err != nil {
+		return nil, errors.Wrap(err, "failed to figure out")
+	}
+
+	return tokens, nil
+}
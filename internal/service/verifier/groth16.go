@@ -0,0 +1,286 @@
+package verifier
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/iden3/go-iden3-crypto/constants"
+	"github.com/iden3/go-rapidsnark/types"
+	"github.com/iden3/go-rapidsnark/verifier/bn256"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// Parsing and pairing code follows github.com/iden3/go-rapidsnark/verifier,
+// which does not export the parsed key and proof types needed for batching.
+
+var ErrInvalidProof = errors.New("invalid proof")
+
+// batchScalarBits is the size of the random coefficients used to combine
+// several proofs into one pairing check
+const batchScalarBits = 128
+
+type proofPairingData struct {
+	A *bn256.G1
+	B *bn256.G2
+	C *bn256.G1
+}
+
+type verificationKey struct {
+	Alpha *bn256.G1
+	Beta  *bn256.G2
+	Gamma *bn256.G2
+	Delta *bn256.G2
+	IC    []*bn256.G1
+}
+
+type verificationKeyJSON struct {
+	Alpha []string   `json:"vk_alpha_1"`
+	Beta  [][]string `json:"vk_beta_2"`
+	Gamma [][]string `json:"vk_gamma_2"`
+	Delta [][]string `json:"vk_delta_2"`
+	IC    [][]string `json:"IC"`
+}
+
+// preparedProof is a proof with its public inputs already folded into the
+// verification key linear combination
+type preparedProof struct {
+	proof proofPairingData
+	vkX   *bn256.G1
+}
+
+func parseVerificationKey(raw []byte) (*verificationKey, error) {
+	var vkJSON verificationKeyJSON
+	if err := json.Unmarshal(raw, &vkJSON); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal verification key")
+	}
+
+	var (
+		vk  verificationKey
+		err error
+	)
+
+	if vk.Alpha, err = stringToG1(vkJSON.Alpha); err != nil {
+		return nil, errors.Wrap(err, "failed to parse alpha")
+	}
+	if vk.Beta, err = stringToG2(vkJSON.Beta); err != nil {
+		return nil, errors.Wrap(err, "failed to parse beta")
+	}
+	if vk.Gamma, err = stringToG2(vkJSON.Gamma); err != nil {
+		return nil, errors.Wrap(err, "failed to parse gamma")
+	}
+	if vk.Delta, err = stringToG2(vkJSON.Delta); err != nil {
+		return nil, errors.Wrap(err, "failed to parse delta")
+	}
+
+	vk.IC = make([]*bn256.G1, 0, len(vkJSON.IC))
+	for _, ic := range vkJSON.IC {
+		p, err := stringToG1(ic)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse IC")
+		}
+		vk.IC = append(vk.IC, p)
+	}
+
+	return &vk, nil
+}
+
+func prepareProof(vk *verificationKey, zkProof types.ZKProof) (*preparedProof, error) {
+	if zkProof.Proof == nil {
+		return nil, errors.New("proof is empty")
+	}
+
+	var (
+		p   proofPairingData
+		err error
+	)
+
+	if p.A, err = stringToG1(zkProof.Proof.A); err != nil {
+		return nil, errors.Wrap(err, "failed to parse pi_a")
+	}
+	if p.B, err = stringToG2(zkProof.Proof.B); err != nil {
+		return nil, errors.Wrap(err, "failed to parse pi_b")
+	}
+	if p.C, err = stringToG1(zkProof.Proof.C); err != nil {
+		return nil, errors.Wrap(err, "failed to parse pi_c")
+	}
+
+	if len(zkProof.PubSignals)+1 != len(vk.IC) {
+		return nil, errors.New("len(inputs)+1 != len(vk.IC)")
+	}
+
+	vkX := new(bn256.G1).ScalarBaseMult(big.NewInt(0))
+	for i, signal := range zkProof.PubSignals {
+		input, err := stringToBigInt(signal)
+		if err != nil {
+			return nil, err
+		}
+		if input.Cmp(constants.Q) != -1 {
+			return nil, errors.New("input value is not in the fields")
+		}
+		vkX = new(bn256.G1).Add(vkX, new(bn256.G1).ScalarMult(vk.IC[i+1], input))
+	}
+	vkX = new(bn256.G1).Add(vkX, vk.IC[0])
+
+	return &preparedProof{proof: p, vkX: vkX}, nil
+}
+
+func verify(vk *verificationKey, p *preparedProof) error {
+	g1 := []*bn256.G1{
+		p.proof.A,
+		new(bn256.G1).Neg(vk.Alpha),
+		new(bn256.G1).Neg(p.vkX),
+		new(bn256.G1).Neg(p.proof.C),
+	}
+	g2 := []*bn256.G2{p.proof.B, vk.Beta, vk.Gamma, vk.Delta}
+
+	if !bn256.PairingCheck(g1, g2) {
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+// verifyBatch checks all the proofs made for the same verification key with
+// a single multi-pairing of n+3 pairs instead of 4n. Every proof is scaled by
+// a fresh random coefficient, so a passing batch implies every proof is valid
+// with overwhelming probability. A failing batch does not say which proof is
+// invalid, callers must fall back to verify.
+func verifyBatch(vk *verificationKey, proofs []*preparedProof) error {
+	if len(proofs) == 1 {
+		return verify(vk, proofs[0])
+	}
+
+	g1 := make([]*bn256.G1, 0, len(proofs)+3)
+	g2 := make([]*bn256.G2, 0, len(proofs)+3)
+
+	coefSum := new(big.Int)
+	vkXSum := new(bn256.G1).ScalarBaseMult(big.NewInt(0))
+	cSum := new(bn256.G1).ScalarBaseMult(big.NewInt(0))
+
+	for _, p := range proofs {
+		coef, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), batchScalarBits))
+		if err != nil {
+			return errors.Wrap(err, "failed to generate batch coefficient")
+		}
+
+		coefSum.Add(coefSum, coef)
+		vkXSum = new(bn256.G1).Add(vkXSum, new(bn256.G1).ScalarMult(p.vkX, coef))
+		cSum = new(bn256.G1).Add(cSum, new(bn256.G1).ScalarMult(p.proof.C, coef))
+
+		g1 = append(g1, new(bn256.G1).ScalarMult(p.proof.A, coef))
+		g2 = append(g2, p.proof.B)
+	}
+
+	g1 = append(g1,
+		new(bn256.G1).Neg(new(bn256.G1).ScalarMult(vk.Alpha, coefSum)),
+		new(bn256.G1).Neg(vkXSum),
+		new(bn256.G1).Neg(cSum),
+	)
+	g2 = append(g2, vk.Beta, vk.Gamma, vk.Delta)
+
+	if !bn256.PairingCheck(g1, g2) {
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+func stringToBigInt(s string) (*big.Int, error) {
+	base := 10
+	if bytes.HasPrefix([]byte(s), []byte("0x")) {
+		base = 16
+		s = strings.TrimPrefix(s, "0x")
+	}
+	n, ok := new(big.Int).SetString(s, base)
+	if !ok {
+		return nil, fmt.Errorf("can not parse string to *big.Int: %s", s)
+	}
+	return n, nil
+}
+
+func stringToG1(h []string) (*bn256.G1, error) {
+	if len(h) <= 2 {
+		return nil, errors.New("not enough data for stringToG1")
+	}
+
+	var b []byte
+	if strings.HasPrefix(h[0], "0x") {
+		raw, err := hex.DecodeString(strings.TrimPrefix(h[0], "0x") + strings.TrimPrefix(h[1], "0x"))
+		if err != nil {
+			return nil, err
+		}
+		b = raw
+	} else {
+		for _, s := range h[:2] {
+			coord, err := stringToBytes(s)
+			if err != nil {
+				return nil, errors.Wrap(err, "error parsing stringToG1")
+			}
+			b = append(b, coord...)
+		}
+	}
+
+	p := new(bn256.G1)
+	_, err := p.Unmarshal(b)
+	return p, err
+}
+
+func stringToG2(h [][]string) (*bn256.G2, error) {
+	if len(h) <= 2 {
+		return nil, errors.New("not enough data for stringToG2")
+	}
+	for _, coords := range h[:2] {
+		if len(coords) < 2 {
+			return nil, errors.New("not enough data for stringToG2")
+		}
+	}
+
+	var b []byte
+	if strings.HasPrefix(h[0][0], "0x") {
+		in := ""
+		for _, coords := range h[:2] {
+			in += strings.TrimPrefix(coords[0], "0x") + strings.TrimPrefix(coords[1], "0x")
+		}
+		raw, err := hex.DecodeString(in)
+		if err != nil {
+			return nil, err
+		}
+		b = raw
+	} else {
+		for _, s := range []string{h[0][1], h[0][0], h[1][1], h[1][0]} {
+			coord, err := stringToBytes(s)
+			if err != nil {
+				return nil, errors.Wrap(err, "error parsing stringToG2")
+			}
+			b = append(b, coord...)
+		}
+	}
+
+	p := new(bn256.G2)
+	_, err := p.Unmarshal(b)
+	return p, err
+}
+
+// stringToBytes encodes a decimal field element as 32 big-endian bytes. The
+// projective "1" coordinate is mapped to zero, so the point at infinity in
+// snarkjs format decodes correctly
+func stringToBytes(s string) ([]byte, error) {
+	if s == "1" {
+		s = "0"
+	}
+	bi, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, errors.New("error parsing bigint stringToBytes")
+	}
+	b := bi.Bytes()
+	if len(b) > 32 {
+		return nil, errors.New("field element is too big")
+	}
+
+	padded := make([]byte, 32)
+	copy(padded[32-len(b):], b)
+	return padded, nil
+}
@@ -0,0 +1,340 @@
+package verifier
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/iden3/go-iden3-crypto/constants"
+	"github.com/iden3/go-rapidsnark/types"
+	"github.com/iden3/go-rapidsnark/verifier/bn256"
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+// testPublicSignals is the amount of the public signals of the passport
+// circuits
+const testPublicSignals = 10
+
+const testRetryAfter = 3 * time.Second
+
+// BenchmarkVerifyGroth16 compares the verification with the key parsed from
+// its JSON on every request to the verification with the key parsed at load
+func BenchmarkVerifyGroth16(b *testing.B) {
+	key := newTestKey(b, testPublicSignals)
+	proof := key.proof(b)
+
+	b.Run("raw_json_key", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			vk, err := parseVerificationKey(key.raw)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			if err := verifyProof(vk, proof); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("parsed_key", func(b *testing.B) {
+		vk, err := parseVerificationKey(key.raw)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := verifyProof(vk, proof); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestVerifyBatch(t *testing.T) {
+	key := newTestKey(t, testPublicSignals)
+	vk := key.parsed(t)
+
+	prepare := func(proofs ...types.ZKProof) []*preparedProof {
+		prepared := make([]*preparedProof, len(proofs))
+		for i, proof := range proofs {
+			p, err := prepareProof(vk, proof)
+			if err != nil {
+				t.Fatal(err)
+			}
+			prepared[i] = p
+		}
+		return prepared
+	}
+
+	valid := func() types.ZKProof { return key.proof(t) }
+	// the signals of another proof do not match the proof points
+	forged := func() types.ZKProof {
+		proof := key.proof(t)
+		proof.PubSignals = key.proof(t).PubSignals
+		return proof
+	}
+
+	tests := []struct {
+		name    string
+		proofs  []types.ZKProof
+		wantErr bool
+	}{
+		{"single valid proof", []types.ZKProof{valid()}, false},
+		{"valid batch", []types.ZKProof{valid(), valid(), valid(), valid()}, false},
+		{"single forged proof", []types.ZKProof{forged()}, true},
+		{"batch with forged proof", []types.ZKProof{valid(), forged(), valid()}, true},
+		// the sum of C is kept, only the random coefficients tell it apart
+		{"batch with swapped points", func() []types.ZKProof {
+			first, second := valid(), valid()
+			first.Proof.C, second.Proof.C = second.Proof.C, first.Proof.C
+			return []types.ZKProof{first, second}
+		}(), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyBatch(vk, prepare(tt.proofs...))
+			if tt.wantErr && err != ErrInvalidProof {
+				t.Fatalf("expected %v, got %v", ErrInvalidProof, err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestProcessBatch(t *testing.T) {
+	first := newTestKey(t, testPublicSignals)
+	second := newTestKey(t, testPublicSignals)
+	v := newTestVerifier(t, 0, map[string]*testKey{"first": first, "second": second})
+
+	forged := first.proof(t)
+	forged.PubSignals = first.proof(t).PubSignals
+
+	tests := []struct {
+		name     string
+		key      string
+		proof    types.ZKProof
+		expected error
+	}{
+		{"first key proof", "first", first.proof(t), nil},
+		{"second key proof", "second", second.proof(t), nil},
+		{"forged proof", "first", forged, ErrInvalidProof},
+		{"another first key proof", "first", first.proof(t), nil},
+		{"proof of another key", "second", first.proof(t), ErrInvalidProof},
+		{"another second key proof", "second", second.proof(t), nil},
+		{"unknown key", "third", first.proof(t), ErrUnknownKey},
+	}
+
+	// all the proofs are verified in one batch, the forged ones fail the
+	// batch of their key and are rejected alone after the fallback
+	batch := make([]*job, len(tests))
+	for i, tt := range tests {
+		batch[i] = &job{key: tt.key, proof: tt.proof, result: make(chan error, 1)}
+	}
+	v.process(batch)
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			select {
+			case err := <-batch[i].result:
+				if errors.Cause(err) != tt.expected {
+					t.Fatalf("expected %v, got %v", tt.expected, err)
+				}
+			default:
+				t.Fatal("proof is not verified")
+			}
+		})
+	}
+}
+
+func TestVerifyQueueFull(t *testing.T) {
+	key := newTestKey(t, testPublicSignals)
+	v := newTestVerifier(t, 1, map[string]*testKey{"key": key})
+
+	// no workers are running, so the first proof takes the only queue slot
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- v.Verify(ctx, "key", key.proof(t))
+	}()
+
+	for len(v.jobs) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := v.Verify(context.Background(), "key", key.proof(t)); err != ErrQueueFull {
+		t.Fatalf("expected %v, got %v", ErrQueueFull, err)
+	}
+
+	cancel()
+	if err := <-done; errors.Cause(err) != context.Canceled {
+		t.Fatalf("expected interrupted verification, got %v", err)
+	}
+
+	if v.RetryAfter() != testRetryAfter {
+		t.Fatalf("expected retry after %s, got %s", testRetryAfter, v.RetryAfter())
+	}
+}
+
+func verifyProof(vk *verificationKey, proof types.ZKProof) error {
+	prepared, err := prepareProof(vk, proof)
+	if err != nil {
+		return err
+	}
+
+	return verify(vk, prepared)
+}
+
+// newTestVerifier returns the verifier of the keys with the given queue size
+// and batching enabled, its workers are not started
+func newTestVerifier(tb testing.TB, queueSize int, keys map[string]*testKey) *Verifier {
+	tb.Helper()
+
+	raw := make(map[string][]byte, len(keys))
+	for name, key := range keys {
+		raw[name] = key.raw
+	}
+
+	v, err := New(logan.New().Out(io.Discard), &config.VerifierConfig{
+		VerificationKeys: raw,
+		Workers:          1,
+		QueueSize:        queueSize,
+		BatchSize:        16,
+		BatchTimeout:     time.Millisecond,
+		RetryAfter:       testRetryAfter,
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return v
+}
+
+// testKey is a verification key built from known random scalars, the valid
+// proofs of random public signals are forged with those scalars instead of
+// a circuit
+type testKey struct {
+	alpha, beta, gamma, delta *big.Int
+	ic                        []*big.Int
+	// raw is the snarkjs JSON of the key
+	raw []byte
+}
+
+func newTestKey(tb testing.TB, publicSignals int) *testKey {
+	tb.Helper()
+
+	key := &testKey{
+		alpha: randScalar(tb),
+		beta:  randScalar(tb),
+		gamma: randScalar(tb),
+		delta: randScalar(tb),
+		ic:    make([]*big.Int, publicSignals+1),
+	}
+
+	icPoints := make([][]string, publicSignals+1)
+	for i := range key.ic {
+		key.ic[i] = randScalar(tb)
+		icPoints[i] = g1JSON(new(bn256.G1).ScalarBaseMult(key.ic[i]))
+	}
+
+	raw, err := json.Marshal(verificationKeyJSON{
+		Alpha: g1JSON(new(bn256.G1).ScalarBaseMult(key.alpha)),
+		Beta:  g2JSON(new(bn256.G2).ScalarBaseMult(key.beta)),
+		Gamma: g2JSON(new(bn256.G2).ScalarBaseMult(key.gamma)),
+		Delta: g2JSON(new(bn256.G2).ScalarBaseMult(key.delta)),
+		IC:    icPoints,
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	key.raw = raw
+
+	return key
+}
+
+func (k *testKey) parsed(tb testing.TB) *verificationKey {
+	tb.Helper()
+
+	vk, err := parseVerificationKey(k.raw)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return vk
+}
+
+// proof returns a valid proof of random public signals
+func (k *testKey) proof(tb testing.TB) types.ZKProof {
+	tb.Helper()
+
+	// x is the discrete log of the linear combination of IC with the inputs
+	x := new(big.Int).Set(k.ic[0])
+	signals := make([]string, len(k.ic)-1)
+	for i := range signals {
+		input := randScalar(tb)
+		signals[i] = input.String()
+		x.Add(x, new(big.Int).Mul(input, k.ic[i+1]))
+	}
+
+	// e(A, B) = e(alpha, beta) * e(vk_x, gamma) * e(C, delta) holds for
+	// a*b = alpha*beta + x*gamma + c*delta
+	a, b := randScalar(tb), randScalar(tb)
+	c := new(big.Int).Mul(a, b)
+	c.Sub(c, new(big.Int).Mul(k.alpha, k.beta))
+	c.Sub(c, new(big.Int).Mul(x, k.gamma))
+	c.Mul(c, new(big.Int).ModInverse(k.delta, constants.Q))
+	c.Mod(c, constants.Q)
+
+	return types.ZKProof{
+		Proof: &types.ProofData{
+			A:        g1JSON(new(bn256.G1).ScalarBaseMult(a)),
+			B:        g2JSON(new(bn256.G2).ScalarBaseMult(b)),
+			C:        g1JSON(new(bn256.G1).ScalarBaseMult(c)),
+			Protocol: "groth16",
+		},
+		PubSignals: signals,
+	}
+}
+
+func randScalar(tb testing.TB) *big.Int {
+	for {
+		n, err := rand.Int(rand.Reader, constants.Q)
+		if err != nil {
+			tb.Fatal(err)
+		}
+
+		if n.Sign() > 0 {
+			return n
+		}
+	}
+}
+
+// g1JSON encodes the affine point as the snarkjs projective coordinates
+func g1JSON(p *bn256.G1) []string {
+	m := p.Marshal()
+	return []string{
+		new(big.Int).SetBytes(m[:32]).String(),
+		new(big.Int).SetBytes(m[32:64]).String(),
+		"1",
+	}
+}
+
+// g2JSON encodes the affine point as the snarkjs projective coordinates, the
+// marshaled coordinates have the imaginary part first
+func g2JSON(p *bn256.G2) [][]string {
+	m := p.Marshal()
+	return [][]string{
+		{new(big.Int).SetBytes(m[32:64]).String(), new(big.Int).SetBytes(m[:32]).String()},
+		{new(big.Int).SetBytes(m[96:128]).String(), new(big.Int).SetBytes(m[64:96]).String()},
+		{"1", "0"},
+	}
+}
@@ -0,0 +1,231 @@
+package verifier
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/iden3/go-rapidsnark/types"
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+)
+
+var (
+	ErrQueueFull  = errors.New("verification queue is full")
+	ErrUnknownKey = errors.New("unknown verification key")
+)
+
+// Verifier checks Groth16 proofs on a bounded pool of workers. Requests are
+// rejected with ErrQueueFull instead of piling up when the queue is saturated.
+type Verifier struct {
+	log          *logan.Entry
+	keysPaths    map[string]string
+	keysMu       sync.RWMutex
+	keys         map[string]*verificationKey
+	jobs         chan *job
+	workers      int
+	batchSize    int
+	batchTimeout time.Duration
+	retryAfter   time.Duration
+}
+
+type job struct {
+	key    string
+	proof  types.ZKProof
+	result chan error
+}
+
+func New(log *logan.Entry, cfg *config.VerifierConfig) (*Verifier, error) {
+	keys, err := parseVerificationKeys(cfg.VerificationKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Verifier{
+		log:          log,
+		keysPaths:    cfg.VerificationKeysPaths,
+		keys:         keys,
+		jobs:         make(chan *job, cfg.QueueSize),
+		workers:      cfg.Workers,
+		batchSize:    cfg.BatchSize,
+		batchTimeout: cfg.BatchTimeout,
+		retryAfter:   cfg.RetryAfter,
+	}, nil
+}
+
+// ReloadKeys re-reads the verification keys from the configured paths. The
+// old keys are kept if any of the new ones is invalid.
+func (v *Verifier) ReloadKeys() error {
+	raw := make(map[string][]byte, len(v.keysPaths))
+	for name, path := range v.keysPaths {
+		key, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Wrap(err, "failed to read verification key", logan.F{"path": path})
+		}
+		raw[name] = key
+	}
+
+	keys, err := parseVerificationKeys(raw)
+	if err != nil {
+		return err
+	}
+
+	v.keysMu.Lock()
+	v.keys = keys
+	v.keysMu.Unlock()
+
+	return nil
+}
+
+func (v *Verifier) key(name string) (*verificationKey, bool) {
+	v.keysMu.RLock()
+	defer v.keysMu.RUnlock()
+
+	vk, ok := v.keys[name]
+	return vk, ok
+}
+
+func parseVerificationKeys(raw map[string][]byte) (map[string]*verificationKey, error) {
+	keys := make(map[string]*verificationKey, len(raw))
+	for name, key := range raw {
+		vk, err := parseVerificationKey(key)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse verification key", logan.F{"key": name})
+		}
+		keys[name] = vk
+	}
+
+	return keys, nil
+}
+
+// Run starts the workers, they are stopped when ctx is done
+func (v *Verifier) Run(ctx context.Context) {
+	v.log.WithFields(logan.F{
+		"workers":    v.workers,
+		"queue_size": cap(v.jobs),
+		"batch_size": v.batchSize,
+	}).Info("starting proof verification workers")
+
+	for i := 0; i < v.workers; i++ {
+		go v.worker(ctx)
+	}
+}
+
+// RetryAfter is the delay suggested to clients rejected with ErrQueueFull
+func (v *Verifier) RetryAfter() time.Duration {
+	return v.retryAfter
+}
+
+// Verify enqueues the proof for verification against the key registered
+// under the given name and waits for the result
+func (v *Verifier) Verify(ctx context.Context, key string, proof types.ZKProof) error {
+	j := &job{
+		key:    key,
+		proof:  proof,
+		result: make(chan error, 1),
+	}
+
+	select {
+	case v.jobs <- j:
+	default:
+		return ErrQueueFull
+	}
+
+	select {
+	case err := <-j.result:
+		return err
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "verification was interrupted")
+	}
+}
+
+func (v *Verifier) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-v.jobs:
+			v.process(v.collect(j))
+		}
+	}
+}
+
+// collect waits up to the batch timeout for more jobs to verify together
+// with the first one
+func (v *Verifier) collect(first *job) []*job {
+	batch := []*job{first}
+	if v.batchSize <= 1 {
+		return batch
+	}
+
+	timer := time.NewTimer(v.batchTimeout)
+	defer timer.Stop()
+
+	for len(batch) < v.batchSize {
+		select {
+		case j := <-v.jobs:
+			batch = append(batch, j)
+		case <-timer.C:
+			return batch
+		}
+	}
+
+	return batch
+}
+
+func (v *Verifier) process(batch []*job) {
+	groups := make(map[string][]*job)
+	for _, j := range batch {
+		groups[j.key] = append(groups[j.key], j)
+	}
+
+	for key, jobs := range groups {
+		v.processGroup(key, jobs)
+	}
+}
+
+func (v *Verifier) processGroup(key string, jobs []*job) {
+	vk, ok := v.key(key)
+	if !ok {
+		for _, j := range jobs {
+			j.result <- errors.From(ErrUnknownKey, logan.F{"key": key})
+		}
+		return
+	}
+
+	prepared := make([]*preparedProof, 0, len(jobs))
+	pending := make([]*job, 0, len(jobs))
+	for _, j := range jobs {
+		p, err := prepareProof(vk, j.proof)
+		if err != nil {
+			j.result <- errors.Wrap(err, "failed to parse proof")
+			continue
+		}
+
+		prepared = append(prepared, p)
+		pending = append(pending, j)
+	}
+
+	if len(pending) == 0 {
+		return
+	}
+
+	if len(pending) > 1 {
+		err := verifyBatch(vk, prepared)
+		if err == nil {
+			for _, j := range pending {
+				j.result <- nil
+			}
+			return
+		}
+
+		v.log.WithError(err).WithField("batch_size", len(pending)).
+			Debug("batch verification failed, verifying proofs one by one")
+	}
+
+	for i, j := range pending {
+		j.result <- verify(vk, prepared[i])
+	}
+}
@@ -0,0 +1,291 @@
+package watcher
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	stateabi "github.com/iden3/contracts-abi/state/go/abi"
+	core "github.com/iden3/go-iden3-core/v2"
+	"github.com/iden3/go-iden3-core/v2/w3c"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rarimo/passport-identity-provider/internal/config"
+	"github.com/rarimo/passport-identity-provider/internal/data"
+	"github.com/rarimo/passport-identity-provider/internal/service/issuer"
+	"gitlab.com/distributed_lab/logan/v3"
+	"gitlab.com/distributed_lab/logan/v3/errors"
+	"gitlab.com/distributed_lab/running"
+)
+
+var (
+	publicationLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "identity_provider_claim_publication_latency_seconds",
+		Help:    "Time from the claim issuance to the on-chain issuer state it was published in",
+		Buckets: []float64{60, 300, 600, 1800, 3600, 3 * 3600, 6 * 3600, 24 * 3600},
+	}, []string{"profile"})
+	unpublishedClaims = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "identity_provider_unpublished_claims",
+		Help: "Active claims whose issuer state is not published on-chain yet",
+	}, []string{"profile"})
+)
+
+// Watcher reads the issuer states from the State contract and records the
+// on-chain state the claims were first published in. The embedded issuer
+// keeps the first state of every claim, for the Issuer Node it is the issuer
+// state of the credential Merkle tree proof.
+type Watcher struct {
+	log     *logan.Entry
+	q       data.MasterQ
+	issuers *issuer.Registry
+	state   *stateabi.State
+	cfg     *config.WatcherConfig
+	// cursors are the last claims checked per issuer, the next batch starts
+	// after them, so the claims not published yet do not hold the rest
+	cursors map[string]*data.Claim
+}
+
+func New(
+	log *logan.Entry, q data.MasterQ, issuers *issuer.Registry, state *stateabi.State, cfg *config.WatcherConfig,
+) *Watcher {
+	return &Watcher{
+		log:     log,
+		q:       q,
+		issuers: issuers,
+		state:   state,
+		cfg:     cfg,
+		cursors: make(map[string]*data.Claim),
+	}
+}
+
+// Run watches the issuer states until ctx is done
+func (w *Watcher) Run(ctx context.Context) {
+	running.WithBackOff(ctx, w.log, "state-watcher", w.watch,
+		w.cfg.Period, w.cfg.Period, w.cfg.Period)
+}
+
+func (w *Watcher) watch(ctx context.Context) error {
+	for profile, iss := range w.issuers.Profiles() {
+		log := w.log.WithFields(logan.F{
+			"profile":    profile,
+			"issuer_did": iss.DID(),
+		})
+
+		if err := w.watchIssuer(ctx, profile, iss); err != nil {
+			// the other issuers are still watched
+			log.WithError(err).Error("failed to watch issuer state")
+		}
+	}
+
+	return nil
+}
+
+func (w *Watcher) watchIssuer(ctx context.Context, profile string, iss *issuer.Issuer) error {
+	id, err := issuerID(iss.DID())
+	if err != nil {
+		return err
+	}
+
+	latest, err := w.latestState(ctx, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to get issuer state")
+	}
+
+	if latest == nil {
+		return nil
+	}
+
+	pending, err := w.q.Claim().
+		FilterBy("issuer_did", iss.DID()).
+		FilterBy("published_at", nil).
+		FilterBy("revoked_at", nil).
+		Count()
+	if err != nil {
+		return errors.Wrap(err, "failed to count unpublished claims")
+	}
+	unpublishedClaims.WithLabelValues(profile).Set(float64(pending))
+
+	claims, err := w.unpublishedClaims(iss.DID())
+	if err != nil {
+		return err
+	}
+
+	// the claims issued after the latest state was created can not be in
+	// it, the next batch starts from the oldest claim again
+	latestCreatedAt := time.Unix(latest.CreatedAtTimestamp.Int64(), 0).UTC()
+	for i, claim := range claims {
+		if claim.CreatedAt.After(latestCreatedAt) {
+			claims = claims[:i]
+			delete(w.cursors, iss.DID())
+			break
+		}
+	}
+
+	published := 0
+	for _, claim := range claims {
+		var state *stateabi.IStateStateInfo
+		if iss.IsEmbedded() {
+			state, err = w.embeddedClaimState(ctx, iss, id, claim)
+		} else {
+			state, err = w.nodeClaimState(ctx, iss, id, claim)
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to get claim state", logan.F{"claim_id": claim.ID.String()})
+		}
+
+		if state == nil {
+			continue
+		}
+
+		publishedAt := time.Unix(state.CreatedAtTimestamp.Int64(), 0).UTC()
+		err = w.q.Claim().FilterBy("id", claim.ID).Update(map[string]any{
+			"published_state": state.State.String(),
+			"published_block": state.CreatedAtBlock.Int64(),
+			"published_at":    publishedAt,
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to mark claim as published", logan.F{"claim_id": claim.ID.String()})
+		}
+
+		publicationLatency.WithLabelValues(profile).Observe(publishedAt.Sub(claim.CreatedAt).Seconds())
+		published++
+	}
+
+	if published > 0 {
+		unpublishedClaims.WithLabelValues(profile).Sub(float64(published))
+		w.log.WithFields(logan.F{
+			"profile": profile,
+			"count":   published,
+		}).Info("claims published")
+	}
+
+	return nil
+}
+
+// unpublishedClaims returns the next batch of the unpublished claims of the
+// issuer after its cursor, the cursor is moved to the last returned claim or
+// reset once the batch is not full
+func (w *Watcher) unpublishedClaims(issuerDID string) ([]data.Claim, error) {
+	q := w.q.Claim().
+		FilterBy("issuer_did", issuerDID).
+		FilterBy("published_at", nil).
+		FilterBy("revoked_at", nil)
+	if cursor := w.cursors[issuerDID]; cursor != nil {
+		q = q.FilterCreatedAfter(cursor.CreatedAt, cursor.ID)
+	}
+
+	claims, err := q.
+		OrderBy("created_at").
+		OrderBy("id").
+		Limit(w.cfg.BatchSize).
+		Select()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select unpublished claims")
+	}
+
+	if uint64(len(claims)) < w.cfg.BatchSize {
+		delete(w.cursors, issuerDID)
+	} else {
+		w.cursors[issuerDID] = &claims[len(claims)-1]
+	}
+
+	return claims, nil
+}
+
+// embeddedClaimState returns the on-chain state of the embedded issuer that
+// first included the claim, nil if the state is not published yet
+func (w *Watcher) embeddedClaimState(
+	ctx context.Context, iss *issuer.Issuer, id *big.Int, claim data.Claim,
+) (*stateabi.IStateStateInfo, error) {
+	state, err := iss.CredentialState(claim.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get credential state")
+	}
+
+	if state == nil {
+		return nil, nil
+	}
+
+	return w.stateInfo(ctx, id, *state)
+}
+
+// nodeClaimState returns the on-chain state of the Issuer Node the credential
+// Merkle tree proof is built against, nil until the credential has the proof
+func (w *Watcher) nodeClaimState(
+	ctx context.Context, iss *issuer.Issuer, id *big.Int, claim data.Claim,
+) (*stateabi.IStateStateInfo, error) {
+	state, err := iss.CredentialMTPState(claim.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get credential proof state")
+	}
+
+	if state == nil {
+		return nil, nil
+	}
+
+	return w.stateInfo(ctx, id, *state)
+}
+
+// stateInfo returns the on-chain info of the issuer state, nil if the state
+// is not published
+func (w *Watcher) stateInfo(ctx context.Context, id *big.Int, stateStr string) (*stateabi.IStateStateInfo, error) {
+	state, ok := new(big.Int).SetString(stateStr, 10)
+	if !ok {
+		return nil, errors.From(errors.New("invalid issuer state"), logan.F{"state": stateStr})
+	}
+
+	opts := &bind.CallOpts{Context: ctx}
+
+	exists, err := w.state.StateExists(opts, id, state)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to check whether state exists")
+	}
+
+	if !exists {
+		return nil, nil
+	}
+
+	info, err := w.state.GetStateInfoByIdAndState(opts, id, state)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get state info")
+	}
+
+	return &info, nil
+}
+
+// latestState returns the latest on-chain state of the issuer, nil if the
+// issuer has not published any state yet
+func (w *Watcher) latestState(ctx context.Context, id *big.Int) (*stateabi.IStateStateInfo, error) {
+	opts := &bind.CallOpts{Context: ctx}
+
+	exists, err := w.state.IdExists(opts, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to check whether issuer state exists")
+	}
+
+	if !exists {
+		return nil, nil
+	}
+
+	state, err := w.state.GetStateInfoById(opts, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get issuer state info")
+	}
+
+	return &state, nil
+}
+
+func issuerID(did string) (*big.Int, error) {
+	parsed, err := w3c.ParseDID(did)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse issuer DID")
+	}
+
+	id, err := core.IDFromDID(*parsed)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get issuer ID")
+	}
+
+	return id.BigInt(), nil
+}
@@ -0,0 +1,13 @@
+/*
+ * GENERATED. Do not modify. Your changes might be overwritten!
+ */
+
+package resources
+
+type BjjSignature struct {
+	// X coordinate of the R8 point
+	R8x string `json:"r8x"`
+	// Y coordinate of the R8 point
+	R8y string `json:"r8y"`
+	S   string `json:"s"`
+}
@@ -4,7 +4,25 @@
 
 package resources
 
+import "time"
+
 type ClaimAttributes struct {
-	ClaimId   string `json:"claim_id"`
-	IssuerDid string `json:"issuer_did"`
+	ClaimId           string     `json:"claim_id"`
+	CreatedAt         *time.Time `json:"created_at,omitempty"`
+	CredentialHash    *string    `json:"credential_hash,omitempty"`
+	CredentialMissing *bool      `json:"credential_missing,omitempty"`
+	CredentialType    *string    `json:"credential_type,omitempty"`
+	ExpiredAt         *time.Time `json:"expired_at,omitempty"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	IsAdult           *bool      `json:"is_adult,omitempty"`
+	IssuerDid         string     `json:"issuer_did"`
+	IssuingAuthority  *int64     `json:"issuing_authority,omitempty"`
+	MtpAvailable      *bool      `json:"mtp_available,omitempty"`
+	Nullifier         *string    `json:"nullifier,omitempty"`
+	PublishedAt       *time.Time `json:"published_at,omitempty"`
+	PublishedBlock    *int64     `json:"published_block,omitempty"`
+	PublishedState    *string    `json:"published_state,omitempty"`
+	Revoked           *bool      `json:"revoked,omitempty"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+	UserDid           *string    `json:"user_did,omitempty"`
 }
@@ -0,0 +1,31 @@
+/*
+ * GENERATED. Do not modify. Your changes might be overwritten!
+ */
+
+package resources
+
+type ClaimProof struct {
+	Key
+	Attributes ClaimProofAttributes `json:"attributes"`
+}
+type ClaimProofResponse struct {
+	Data     ClaimProof `json:"data"`
+	Included Included   `json:"included"`
+}
+
+type ClaimProofListResponse struct {
+	Data     []ClaimProof `json:"data"`
+	Included Included     `json:"included"`
+	Links    *Links       `json:"links"`
+}
+
+// MustClaimProof - returns ClaimProof from include collection.
+// if entry with specified key does not exist - returns nil
+// if entry with specified key exists but type or ID mismatches - panics
+func (c *Included) MustClaimProof(key Key) *ClaimProof {
+	var claimProof ClaimProof
+	if c.tryFindEntry(key, &claimProof) {
+		return &claimProof
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+/*
+ * GENERATED. Do not modify. Your changes might be overwritten!
+ */
+
+package resources
+
+type ClaimProofAttributes struct {
+	// Hex encoded auth claim of the issuer key the claim is signed with
+	AuthClaim string `json:"auth_claim"`
+	// Proof of the auth claim in the claims tree of the state
+	AuthClaimMtp MerkleProof `json:"auth_claim_mtp"`
+	// Proof of the auth claim revocation nonce absence in the revocation tree of the state
+	AuthClaimNonRevProof MerkleProof `json:"auth_claim_non_rev_proof"`
+	// Hex encoded iden3 claim of the credential
+	Claim string `json:"claim"`
+	// Claims tree root of the state
+	ClaimsRoot string `json:"claims_root"`
+	// DID of the embedded issuer
+	IssuerDid string `json:"issuer_did"`
+	// Proof of the claim in the claims tree of the state, missing until the claim is included in the published state
+	Mtp *MerkleProof `json:"mtp,omitempty"`
+	// Proof of the claim revocation nonce absence in the revocation tree of the state, the nonce exists if the claim is revoked
+	NonRevProof MerkleProof `json:"non_rev_proof"`
+	// Revocation tree root of the state
+	RevocationRoot string `json:"revocation_root"`
+	// Roots tree root of the state
+	RootsRoot string `json:"roots_root"`
+	// Hex encoded compressed BabyJubJub signature of the Poseidon hash of the claim index and value hashes
+	Signature string `json:"signature"`
+	// Latest published state of the issuer the proofs are built against
+	State string `json:"state"`
+}
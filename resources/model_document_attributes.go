@@ -0,0 +1,15 @@
+/*
+ * GENERATED. Do not modify. Your changes might be overwritten!
+ */
+
+package resources
+
+import "time"
+
+type DocumentAttributes struct {
+	BanReason         *string   `json:"ban_reason,omitempty"`
+	FirstSeenAt       time.Time `json:"first_seen_at"`
+	IsBanned          bool      `json:"is_banned"`
+	PolicyVersion     int64     `json:"policy_version"`
+	RegistrationCount int64     `json:"registration_count"`
+}
@@ -0,0 +1,31 @@
+/*
+ * GENERATED. Do not modify. Your changes might be overwritten!
+ */
+
+package resources
+
+type DocumentBan struct {
+	Key
+	Attributes DocumentBanAttributes `json:"attributes"`
+}
+type DocumentBanResponse struct {
+	Data     DocumentBan `json:"data"`
+	Included Included    `json:"included"`
+}
+
+type DocumentBanListResponse struct {
+	Data     []DocumentBan `json:"data"`
+	Included Included      `json:"included"`
+	Links    *Links        `json:"links"`
+}
+
+// MustDocumentBan - returns DocumentBan from include collection.
+// if entry with specified key does not exist - returns nil
+// if entry with specified key exists but type or ID mismatches - panics
+func (c *Included) MustDocumentBan(key Key) *DocumentBan {
+	var documentBan DocumentBan
+	if c.tryFindEntry(key, &documentBan) {
+		return &documentBan
+	}
+	return nil
+}
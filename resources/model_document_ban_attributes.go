@@ -0,0 +1,10 @@
+/*
+ * GENERATED. Do not modify. Your changes might be overwritten!
+ */
+
+package resources
+
+type DocumentBanAttributes struct {
+	// Reason of the ban or its lifting, it is recorded in the audit log
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,14 @@
+/*
+ * GENERATED. Do not modify. Your changes might be overwritten!
+ */
+
+package resources
+
+type HealthAttributes struct {
+	// State of the Issuer Node circuit breaker
+	IssuerBreaker string `json:"issuer_breaker"`
+	// States of the circuit breakers of all Issuer Node profiles by the profile names
+	IssuerBreakers map[string]string `json:"issuer_breakers"`
+	// Service is healthy if all of its dependencies are available
+	Healthy bool `json:"healthy"`
+}
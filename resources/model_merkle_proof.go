@@ -0,0 +1,13 @@
+/*
+ * GENERATED. Do not modify. Your changes might be overwritten!
+ */
+
+package resources
+
+type MerkleProof struct {
+	// Whether the key exists in the tree
+	Existence bool                `json:"existence"`
+	NodeAux   *MerkleProofNodeAux `json:"node_aux,omitempty"`
+	// Siblings from the root down to the leaf of the key
+	Siblings []string `json:"siblings"`
+}
@@ -0,0 +1,10 @@
+/*
+ * GENERATED. Do not modify. Your changes might be overwritten!
+ */
+
+package resources
+
+type MerkleProofNodeAux struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
@@ -0,0 +1,21 @@
+/*
+ * GENERATED. Do not modify. Your changes might be overwritten!
+ */
+
+package resources
+
+import "time"
+
+type RegistrationAttributes struct {
+	// ID of the issued claim, set once the status is issued
+	ClaimId   *string   `json:"claim_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// Application-specific code of the failure, set once the status is failed
+	FailureCode *string `json:"failure_code,omitempty"`
+	// Reason of the failure, set once the status is failed
+	FailureReason *string `json:"failure_reason,omitempty"`
+	// DID of the issuer, set once the status is issued
+	IssuerDid *string   `json:"issuer_did,omitempty"`
+	Status    string    `json:"status"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -8,6 +8,12 @@ type ResourceType string
 
 // List of ResourceType
 const (
-	CLAIMS     ResourceType = "claims"
-	GIST_DATAS ResourceType = "gist_datas"
+	CLAIM_PROOFS      ResourceType = "claim_proofs"
+	CLAIMS            ResourceType = "claims"
+	DOCUMENTS         ResourceType = "documents"
+	DOCUMENT_BANS     ResourceType = "document_bans"
+	GIST_DATAS        ResourceType = "gist_datas"
+	HEALTH            ResourceType = "health"
+	REGISTRATIONS     ResourceType = "registrations"
+	STATE_TRANSITIONS ResourceType = "state_transitions"
 )
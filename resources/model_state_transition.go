@@ -0,0 +1,31 @@
+/*
+ * GENERATED. Do not modify. Your changes might be overwritten!
+ */
+
+package resources
+
+type StateTransition struct {
+	Key
+	Attributes StateTransitionAttributes `json:"attributes"`
+}
+type StateTransitionResponse struct {
+	Data     StateTransition `json:"data"`
+	Included Included        `json:"included"`
+}
+
+type StateTransitionListResponse struct {
+	Data     []StateTransition `json:"data"`
+	Included Included          `json:"included"`
+	Links    *Links            `json:"links"`
+}
+
+// MustStateTransition - returns StateTransition from include collection.
+// if entry with specified key does not exist - returns nil
+// if entry with specified key exists but type or ID mismatches - panics
+func (c *Included) MustStateTransition(key Key) *StateTransition {
+	var stateTransition StateTransition
+	if c.tryFindEntry(key, &stateTransition) {
+		return &stateTransition
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+/*
+ * GENERATED. Do not modify. Your changes might be overwritten!
+ */
+
+package resources
+
+import "time"
+
+type StateTransitionAttributes struct {
+	// Hex encoded auth claim of the issuer key the transition is signed with
+	AuthClaim string `json:"auth_claim"`
+	// Proof of the auth claim in the claims tree of the old state
+	AuthClaimMtp MerkleProof `json:"auth_claim_mtp"`
+	// Proof of the auth claim revocation nonce absence in the revocation tree of the old state
+	AuthClaimNonRevProof MerkleProof `json:"auth_claim_non_rev_proof"`
+	// Claims tree root of the new state
+	ClaimsRoot string `json:"claims_root"`
+	// Time the transition was recorded
+	CreatedAt time.Time `json:"created_at"`
+	// Whether the old state is the genesis state, which is not published on-chain
+	IsOldStateGenesis bool `json:"is_old_state_genesis"`
+	// DID of the embedded issuer
+	IssuerDid string `json:"issuer_did"`
+	// Proof of the auth claim in the claims tree of the new state
+	NewAuthClaimMtp MerkleProof `json:"new_auth_claim_mtp"`
+	// State to be published
+	NewState string `json:"new_state"`
+	// Claims tree root of the old state
+	OldClaimsRoot string `json:"old_claims_root"`
+	// Revocation tree root of the old state
+	OldRevocationRoot string `json:"old_revocation_root"`
+	// Roots tree root of the old state
+	OldRootsRoot string `json:"old_roots_root"`
+	// Last published state of the issuer
+	OldState string `json:"old_state"`
+	// Revocation tree root of the new state
+	RevocationRoot string `json:"revocation_root"`
+	// Roots tree root of the new state
+	RootsRoot string `json:"roots_root"`
+	// Signature of the Poseidon hash of the old and new states
+	Signature BjjSignature `json:"signature"`
+}